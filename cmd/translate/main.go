@@ -2,14 +2,25 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/joho/godotenv"
 	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/format"
 	"github.com/smilingpoplar/translate/translator"
+	"github.com/smilingpoplar/translate/translator/middleware"
 	"github.com/smilingpoplar/translate/util"
 	"github.com/spf13/cobra"
 )
@@ -17,26 +28,41 @@ import (
 const (
 	kService   = "service"
 	kTolang    = "tolang"
+	kFromlang  = "fromlang"
 	kEnvFile   = "envfile"
 	kProxy     = "proxy"
 	KGlossFile = "glossfile"
 	kInput     = "input"
 	kOutput    = "output"
+	kFormat    = "format"
+	kKeys      = "keys"
+	kBilingual = "bilingual"
+	kNoPass    = "no-passthrough"
 )
 
 var (
 	service   string
 	tolang    string
+	fromlang  string
 	envfile   string
 	proxy     string
 	glossfile string
 	input     string
 	output    string
+	docFormat string
+	keys      []string
+	bilingual string
+	noPass    bool
 )
 
 func main() {
+	// Ctrl-C时取消进行中的翻译，让已打开的文件正常关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	cmd := initCmd()
-	if err := cmd.Execute(); err != nil {
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		stop()
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -50,23 +76,55 @@ func initCmd() *cobra.Command {
   translate -i input.txt -o output.txt`,
 		DisableFlagsInUseLine: true,
 		SilenceErrors:         true,
+		Args:                  cobra.ArbitraryArgs, // 有子命令时cobra默认把参数当作子命令名
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := initEnv(); err != nil {
 				return err
 			}
-			return translate(args)
+			return translate(cmd.Context(), args)
 		},
 	}
 
 	services := fmt.Sprintf("translate service, eg. %s", strings.Join(config.GetAllServiceNames(), ", "))
 	cmd.Flags().StringVarP(&service, kService, "s", "google", services)
-	cmd.Flags().StringVarP(&tolang, kTolang, "t", "zh-CN", "target language")
+	cmd.Flags().StringVarP(&tolang, kTolang, "t", "zh-CN", "target language, comma separated for multiple languages,\n eg. ja,ko,fr with -o out.{lang}.md")
+	cmd.Flags().StringVar(&fromlang, kFromlang, "", "source language, detected by the service if not set")
 	cmd.Flags().StringVarP(&envfile, kEnvFile, "e", "", "env file, search .env upwards if not set")
-	cmd.Flags().StringVarP(&glossfile, KGlossFile, "g", "", "csv file for glossary")
+	cmd.Flags().StringVarP(&glossfile, KGlossFile, "g", "", "glossary file (csv or tbx)")
 	cmd.Flags().StringVarP(&input, kInput, "i", "", "input file, if set then stdin/pipe is ignored")
-	cmd.Flags().StringVarP(&output, kOutput, "o", "", "output file, if set then stdout redirection is ignored")
+	cmd.Flags().StringVarP(&output, kOutput, "o", "", "output file, if set then stdout redirection is ignored,\n {lang} is replaced with the target language")
+	formats := fmt.Sprintf("input format, detected from the input file extension if not set,\n eg. %s", strings.Join(format.Names(), ", "))
+	cmd.Flags().StringVarP(&docFormat, kFormat, "f", "", formats)
+	cmd.Flags().StringSliceVar(&keys, kKeys, nil, "only translate these keys in json/yaml,\n eg. $.home.*,errors.**")
+	modes := strings.Join(util.BilingualModes, "|")
+	cmd.Flags().StringVar(&bilingual, kBilingual, "", "keep the original, "+modes+" for plain text,\n each translated paragraph follows the original in documents (html, epub)")
+	cmd.Flags().Lookup(kBilingual).NoOptDefVal = util.BilingualInterleave
+	cmd.Flags().BoolVar(&noPass, kNoPass, false, "also send texts already in the target language, numbers, urls and code\n to the service")
 	cmd.Flags().StringVarP(&proxy, kProxy, "p", "", "http or socks5 proxy,\n eg. http://127.0.0.1:7890 or socks5://127.0.0.1:7890")
 
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	cmd.AddCommand(initDetectCmd(services))
+	return cmd
+}
+
+func initDetectCmd(services string) *cobra.Command {
+	cmd := &cobra.Command{
+		Short: "detect the language of each line",
+		Use: `detect "hello world"
+  cat input.txt | translate detect`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initEnv(); err != nil {
+				return err
+			}
+			return detect(cmd.Context(), args)
+		},
+	}
+
+	cmd.Flags().StringVarP(&service, kService, "s", "google", services+",\n services without language detection use the offline detector")
+	cmd.Flags().StringVarP(&envfile, kEnvFile, "e", "", "env file, search .env upwards if not set")
+	cmd.Flags().StringVarP(&input, kInput, "i", "", "input file, if set then stdin/pipe is ignored")
+	cmd.Flags().StringVarP(&proxy, kProxy, "p", "", "http or socks5 proxy,\n eg. http://127.0.0.1:7890 or socks5://127.0.0.1:7890")
 	return cmd
 }
 
@@ -92,7 +150,28 @@ func initEnv() error {
 	return nil
 }
 
-func translate(args []string) error {
+// target 一种目标语言及其输出文件，output为""时输出到标准输出
+type target struct {
+	lang   string
+	output string
+}
+
+func translate(ctx context.Context, args []string) error {
+	if bilingual != "" && !slices.Contains(util.BilingualModes, bilingual) {
+		return fmt.Errorf("unsupported bilingual mode %q, expect one of: %s", bilingual, strings.Join(util.BilingualModes, ", "))
+	}
+	langs := splitLangs(tolang)
+	if len(langs) == 0 {
+		return fmt.Errorf("no target language")
+	}
+	if len(langs) > 1 && !strings.Contains(output, langPattern) {
+		return fmt.Errorf("multiple target languages need -o with %s, eg. out.%s.md", langPattern, langPattern)
+	}
+
+	if noPass {
+		ctx = middleware.WithoutPassThrough(ctx)
+	}
+
 	glossary, err := util.LoadGlossary(glossfile)
 	if err != nil {
 		return err
@@ -110,42 +189,280 @@ func translate(args []string) error {
 		return err
 	}
 	if reader == nil { // 从终端交互读取要翻译的文本
-		return translateInTerminal(trans)
+		if len(langs) > 1 {
+			return fmt.Errorf("multiple target languages need input from -i, pipe or arguments")
+		}
+		return translateInTerminal(ctx, trans, langs[0])
 	}
 	if c, ok := reader.(io.Closer); ok {
 		defer c.Close()
 	}
-	writer, err := getOutputWriter()
+
+	name := docFormat
+	if name == "" {
+		name = format.Detect(input)
+	}
+	if len(langs) > 1 {
+		return translateLangs(ctx, trans, name, reader, langs)
+	}
+
+	t := target{lang: langs[0], output: strings.ReplaceAll(output, langPattern, langs[0])}
+	writer, err := getOutputWriter(t.output)
 	if err != nil {
 		return err
 	}
 	if c, ok := writer.(io.Closer); ok {
 		defer c.Close()
 	}
-
-	o, ok := trans.(translator.TranslationObserver)
-	if ok { // 收到分组响应后立即输出
-		o.OnTranslated(func(translated []string) error {
-			return util.WriteLines(writer, translated)
-		})
+	if name != format.Text { // 按文档结构翻译
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+		doc, err := parseDocument(name, data, t.lang)
+		if err != nil {
+			return err
+		}
+		return translateDocument(ctx, trans, doc, data, writer, t)
 	}
+
 	texts, err := util.ReadLines(reader)
 	if err != nil {
 		return err
 	}
-	result, err := trans.Translate(texts, tolang)
+	return translateText(ctx, trans, texts, writer, t, true)
+}
+
+// -o中代表目标语言的部分
+const langPattern = "{lang}"
+
+// splitLangs 拆分逗号分隔的目标语言，去掉空白和重复
+func splitLangs(s string) []string {
+	var langs []string
+	for _, lang := range strings.Split(s, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" && !slices.Contains(langs, lang) {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// translateLangs 输入只读取和解析一次，各目标语言共用同一个翻译服务并发翻译，
+// 服务的限速、并发数、缓存和术语表对所有语言生效
+func translateLangs(ctx context.Context, trans translator.Translator, name string, r io.Reader, langs []string) error {
+	data, err := io.ReadAll(r)
 	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+	var texts []string
+	var doc format.Document
+	if name == format.Text {
+		if texts, err = util.ReadLines(bytes.NewReader(data)); err != nil {
+			return err
+		}
+	} else if doc, err = parseDocument(name, data, langs[0]); err != nil {
 		return err
 	}
-	if !ok { // 收到全部响应后再输出
-		if err = util.WriteLines(writer, result); err != nil {
+	// 复数形式等结构与目标语言有关（PO、Android、Apple）或渲染时会修改文档树（HTML、EPUB、YAML）
+	// 的格式不能共用解析结果，其余目标语言重新解析
+	_, reusable := doc.(format.Reusable)
+
+	// 任一语言出错时取消其他语言
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(langs))
+	var wg sync.WaitGroup
+	for i, lang := range langs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t := target{lang: lang, output: strings.ReplaceAll(output, langPattern, lang)}
+			doc := doc
+			if i > 0 && !reusable {
+				doc = nil
+			}
+			if err := translateTo(ctx, trans, name, data, texts, doc, t); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", lang, err)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 只报告引起取消的错误
+	var failed []error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == 0 {
+		failed = errs
+	}
+	return errors.Join(failed...)
+}
+
+// translateTo 将同一份输入翻译成t.lang，写入t.output。文档doc为nil时按t.lang重新解析
+func translateTo(ctx context.Context, trans translator.Translator, name string, data []byte, texts []string, doc format.Document, t target) error {
+	if doc == nil && name != format.Text {
+		var err error
+		if doc, err = parseDocument(name, data, t.lang); err != nil {
+			return err
+		}
+	}
+
+	w, err := getOutputWriter(t.output)
+	if err != nil {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok {
+		defer c.Close()
+	}
+	if name != format.Text {
+		return translateDocument(ctx, trans, doc, data, w, t)
+	}
+	return translateText(ctx, trans, texts, w, t, false)
+}
+
+// translateText 逐行翻译纯文本，stream时分组响应按原文顺序流式输出
+func translateText(ctx context.Context, trans translator.Translator, texts []string, w io.Writer, t target, stream bool) error {
+	write := func(translated []string) error {
+		return util.WriteLines(w, translated)
+	}
+	if bilingual != "" { // 原文和译文一起输出
+		bw, err := util.NewBilingualWriter(w, bilingual, texts, outputWidth(t.output))
+		if err != nil {
 			return err
 		}
+		write = bw.WriteLines
+	}
+
+	o, ok := trans.(translator.TranslationObserver)
+	ok = ok && stream
+	if ok {
+		o.OnTranslated(write)
+	}
+	result, err := trans.TranslateContext(ctx, texts, fromlang, t.lang)
+	if err != nil {
+		return err
+	}
+	if !ok { // 收到全部响应后再输出
+		return write(result)
 	}
+	return nil
+}
+
+// parseDocument 按目标语言toLang解析文档
+func parseDocument(name string, data []byte, toLang string) (format.Document, error) {
+	return format.Parse(name, data, format.Options{ToLang: toLang, Keys: keys, Bilingual: bilingual != ""})
+}
 
+// translateDocument 翻译文档中的文本片段后按原有结构输出，data为原文，用于校验保存的进度
+func translateDocument(ctx context.Context, trans translator.Translator, doc format.Document, data []byte, w io.Writer, t target) error {
+	texts := doc.Texts()
+	result := texts
+	var err error
+	var checkpoint *util.Checkpoint
+	if ch, ok := doc.(format.Chaptered); ok && t.output != "" { // 长文档逐章翻译并保存进度
+		if checkpoint, err = util.LoadCheckpoint(t.output+".progress", checkpointKey(data, t.lang)); err != nil {
+			return err
+		}
+		if result, err = translateChapters(ctx, trans, ch, checkpoint, t.lang); err != nil {
+			return err
+		}
+	} else if len(texts) > 0 {
+		if result, err = trans.TranslateContext(ctx, texts, fromlang, t.lang); err != nil {
+			return err
+		}
+	}
+	if r, ok := doc.(format.Reusable); ok {
+		err = r.RenderLang(w, result, t.lang)
+	} else {
+		err = doc.Render(w, result)
+	}
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil {
+		return checkpoint.Remove()
+	}
 	return nil
 }
 
+// translateChapters 逐章翻译，每章完成后保存进度，中断后重新运行时跳过已完成的章节
+func translateChapters(ctx context.Context, trans translator.Translator, doc format.Chaptered, checkpoint *util.Checkpoint, toLang string) ([]string, error) {
+	texts := doc.Texts()
+	result := make([]string, 0, len(texts))
+	start := 0
+	for i, n := range doc.Chapters() {
+		chapter := texts[start : start+n]
+		start += n
+		if i < len(checkpoint.Chapters) && len(checkpoint.Chapters[i]) == n {
+			result = append(result, checkpoint.Chapters[i]...)
+			continue
+		}
+
+		checkpoint.Chapters = checkpoint.Chapters[:min(i, len(checkpoint.Chapters))]
+		translated, err := trans.TranslateContext(ctx, chapter, fromlang, toLang)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkpoint.Save(translated); err != nil {
+			return nil, err
+		}
+		result = append(result, translated...)
+	}
+	return result, nil
+}
+
+// checkpointKey 原文和影响译文的选项变化后，之前的进度作废
+func checkpointKey(data []byte, toLang string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%q\x00", service, fromlang, toLang, keys)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// detect 逐行输出检测到的语言、置信度和原文，无法判断的语言输出为-
+func detect(ctx context.Context, args []string) error {
+	trans, err := translator.GetTranslator(service, proxy, nil)
+	if err != nil {
+		return err
+	}
+	if c, ok := trans.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	reader, err := getInputReader(args)
+	if err != nil {
+		return err
+	}
+	if reader == nil {
+		return fmt.Errorf("no input, pass texts as arguments or pipe them in")
+	}
+	if c, ok := reader.(io.Closer); ok {
+		defer c.Close()
+	}
+	texts, err := util.ReadLines(reader)
+	if err != nil {
+		return err
+	}
+
+	detections, err := translator.DetectContext(ctx, trans, texts)
+	if err != nil {
+		return err
+	}
+	lines := make([]string, len(texts))
+	for i, d := range detections {
+		lang := d.Lang
+		if lang == "" {
+			lang = "-"
+		}
+		lines[i] = fmt.Sprintf("%s\t%.2f\t%s", lang, d.Confidence, texts[i])
+	}
+	return util.WriteLines(os.Stdout, lines)
+}
+
 func getInputReader(args []string) (io.Reader, error) {
 	if input != "" { // 从-i读取要翻译的文本
 		f, err := os.Open(input)
@@ -155,18 +472,18 @@ func getInputReader(args []string) (io.Reader, error) {
 		return f, nil
 	}
 
-	if !util.IsTerminal() { // 从os.Stdin读取要翻译的文本
-		return os.Stdin, nil
+	if len(args) > 0 { // 翻译命令行参数，优先于非终端的stdin（如脚本、CI中运行）
+		return strings.NewReader(strings.Join(args, "\n")), nil
 	}
 
-	if len(args) > 0 { // 翻译命令行参数
-		return strings.NewReader(strings.Join(args, "\n")), nil
+	if !util.IsTerminal() { // 从os.Stdin读取要翻译的文本
+		return os.Stdin, nil
 	}
 
 	return nil, nil
 }
 
-func getOutputWriter() (io.Writer, error) {
+func getOutputWriter(output string) (io.Writer, error) {
 	if output != "" { // 向-o写入翻译结果
 		f, err := os.Create(output)
 		if err != nil {
@@ -177,19 +494,48 @@ func getOutputWriter() (io.Writer, error) {
 	return os.Stdout, nil
 }
 
-func translateInTerminal(trans translator.Translator) error {
+// outputWidth 输出到终端时为终端宽度，否则为0
+func outputWidth(output string) int {
+	if output != "" {
+		return 0
+	}
+	return util.TerminalWidth()
+}
+
+func translateInTerminal(ctx context.Context, trans translator.Translator, toLang string) error {
 	fmt.Println("Input texts to be translated... <Ctrl-D> to finish.")
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		text := strings.TrimSpace(scanner.Text())
+	// 读终端会阻塞，放到goroutine中，以便Ctrl-C时立即退出
+	lines := make(chan string)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		errc <- scanner.Err()
+	}()
+
+	for {
+		var line string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok = <-lines:
+		}
+		if !ok {
+			return <-errc
+		}
+
+		text := strings.TrimSpace(line)
 		if text == "" {
 			continue
 		}
-		result, err := trans.Translate([]string{text}, tolang)
+		result, err := trans.TranslateContext(ctx, []string{text}, fromlang, toLang)
 		if err != nil {
 			return err
 		}
 		fmt.Fprintln(os.Stdout, result[0])
 	}
-	return scanner.Err()
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestService 启动LibreTranslate接口的stand-in服务，译文为"目标语言:原文"。
+// 目标语言为hang的请求一直等到客户端取消
+func newTestService(t *testing.T, hang string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/languages":
+			json.NewEncoder(w).Encode([]map[string]string{{"code": "zh"}, {"code": "ja"}, {"code": "fr"}})
+		case "/translate":
+			var req struct {
+				Q      []string `json:"q"`
+				Target string   `json:"target"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Target == hang {
+				<-r.Context().Done()
+				return
+			}
+			translated := make([]string, len(req.Q))
+			for i, q := range req.Q {
+				translated[i] = req.Target + ":" + q
+			}
+			json.NewEncoder(w).Encode(map[string]any{"translatedText": translated})
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("TMPDIR", t.TempDir()) // 隔离缓存文件
+	t.Setenv("LIBRETRANSLATE_BASE_URL", server.URL)
+}
+
+func runCmd(t *testing.T, args ...string) error {
+	t.Helper()
+	cmd := initCmd()
+	cmd.SetArgs(args)
+	return cmd.Execute()
+}
+
+func TestRootCmd_PositionalArgs(t *testing.T) {
+	newTestService(t, "")
+	out := filepath.Join(t.TempDir(), "out.txt")
+
+	if err := runCmd(t, "-s", "libretranslate", "-o", out, "hello world"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(got)) != "zh:hello world" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestRootCmd_NoPassThrough(t *testing.T) {
+	newTestService(t, "")
+	dir := t.TempDir()
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "12345"},
+		{[]string{"--no-passthrough"}, "zh:12345"},
+	} {
+		out := filepath.Join(dir, "out.txt")
+		args := append([]string{"-s", "libretranslate", "-o", out, "12345"}, tt.args...)
+		if err := runCmd(t, args...); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(got)) != tt.want {
+			t.Errorf("args %q: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRootCmd_MultipleLangs(t *testing.T) {
+	newTestService(t, "")
+	dir := t.TempDir()
+
+	for _, tt := range []struct {
+		ext, src string
+		want     map[string]string
+	}{
+		// 解析一次后按各目标语言渲染
+		{".json", `{"en": {"hello": "Hello"}}`, map[string]string{
+			"ja": `{"ja": {"hello": "ja:Hello"}}`,
+			"fr": `{"fr": {"hello": "fr:Hello"}}`,
+		}},
+		// 文档头随目标语言变化，每种目标语言重新解析
+		{".po", "msgid \"\"\nmsgstr \"\"\n\"Language: en\\n\"\n\nmsgid \"Hello\"\nmsgstr \"\"\n", map[string]string{
+			"ja": "\"Language: ja\\n\"\n\n#, fuzzy\nmsgid \"Hello\"\nmsgstr \"ja:Hello\"\n",
+			"fr": "\"Language: fr\\n\"\n\n#, fuzzy\nmsgid \"Hello\"\nmsgstr \"fr:Hello\"\n",
+		}},
+	} {
+		in := filepath.Join(dir, "in"+tt.ext)
+		if err := os.WriteFile(in, []byte(tt.src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, "out.{lang}"+tt.ext)
+		if err := runCmd(t, "-s", "libretranslate", "-t", "ja,fr", "-i", in, "-o", out); err != nil {
+			t.Fatal(err)
+		}
+		for lang, want := range tt.want {
+			got, err := os.ReadFile(strings.ReplaceAll(out, "{lang}", lang))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), want) {
+				t.Errorf("%s %s: output %q missing %q", tt.ext, lang, got, want)
+			}
+		}
+	}
+}
+
+func TestRootCmd_MultipleLangsError(t *testing.T) {
+	// de不受支持，出错后取消还在等待的ja，只报告引起取消的错误
+	newTestService(t, "ja")
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.md")
+	if err := os.WriteFile(in, []byte("Hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runCmd(t, "-s", "libretranslate", "-t", "ja,de", "-i", in, "-o", filepath.Join(dir, "out.{lang}.md"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "de: ") || strings.Contains(msg, "ja: ") {
+		t.Errorf("unexpected error %q", msg)
+	}
+}
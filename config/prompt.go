@@ -7,8 +7,14 @@ import (
 	"strings"
 )
 
-func GetPrompt(texts []string, toLang string) (string, error) {
+// GetPrompt fromLang为空时由模型自行判断源语言
+func GetPrompt(texts []string, fromLang, toLang string) (string, error) {
 	str := getPromptTemplate()
+	from := ""
+	if fromLang != "" {
+		from = fmt.Sprintf(` from "%s"`, fromLang)
+	}
+	str = strings.ReplaceAll(str, "{{from}}", from)
 	str = strings.ReplaceAll(str, "{{lang}}", toLang)
 	jsonStr, err := getJson(texts)
 	if err != nil {
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetPromptFromLang(t *testing.T) {
+	t.Parallel()
+
+	prompt, err := GetPrompt([]string{"hello"}, "en", "ja")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, `"text" field from "en" into "ja"`) {
+		t.Errorf("prompt should mention source language:\n%s", prompt)
+	}
+
+	prompt, err = GetPrompt([]string{"hello"}, "", "ja")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(prompt, "{{from}}") || !strings.Contains(prompt, `"text" field into "ja"`) {
+		t.Errorf("prompt without source language:\n%s", prompt)
+	}
+}
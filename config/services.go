@@ -37,10 +37,17 @@ func NewServiceConfig(service string) *ServiceConfig {
 	// OpenAI兼容类型：先拷贝openai配置
 	if svcYAML.Type == kOpenAI {
 		svc.setToOpenAICompatible()
+	} else if base, ok := servicesYAML[svcYAML.Type]; ok && svcYAML.Type != service {
+		// 其他类型：先拷贝同名基础服务的配置，比如type: anthropic
+		svc.Type = svcYAML.Type
+		svc.YAML = base.copy()
 	}
 
 	// 自身的配置
 	svc.YAML = merge(svc.YAML, svcYAML)
+	if svc.YAML.Type != "" {
+		svc.Type = svc.YAML.Type
+	}
 	return svc
 }
 
@@ -122,7 +129,47 @@ func (svc *ServiceConfig) GetExtraBody() map[string]any {
 	return nil
 }
 
+// 环境变量配置逗号分隔的服务名，比如glm,siliconflow,google
+func (svc *ServiceConfig) GetServices() []string {
+	if s := svc.GetEnvValue(kServices); s != "" {
+		var names []string
+		for name := range strings.SplitSeq(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	if svc.YAML != nil {
+		return svc.YAML.Services
+	}
+	return nil
+}
+
+// 环境变量配置JSON数组，比如[{"to":["ja","ko"],"service":"glm"},{"service":"google"}]
+func (svc *ServiceConfig) GetRoutes() []Route {
+	if s := svc.GetEnvValue(kRoutes); s != "" {
+		var routes []Route
+		if err := json.Unmarshal([]byte(s), &routes); err == nil {
+			return routes
+		} else {
+			log.Printf("Warning: failed to parse routes for %s: %v", svc.Name, err)
+		}
+	}
+
+	if svc.YAML != nil {
+		return svc.YAML.Routes
+	}
+	return nil
+}
+
 func (svc *ServiceConfig) GetRpm() int {
+	return svc.GetRpmOrDefault(60)
+}
+
+// GetRpmOrDefault 未配置rpm时返回def
+func (svc *ServiceConfig) GetRpmOrDefault(def int) int {
 	if s := svc.GetEnvValue(kRpm); s != "" {
 		if rpm, err := strconv.Atoi(s); err == nil {
 			return rpm
@@ -134,7 +181,7 @@ func (svc *ServiceConfig) GetRpm() int {
 	if svc.YAML != nil && svc.YAML.Rpm > 0 {
 		return svc.YAML.Rpm
 	}
-	return 60
+	return def
 }
 
 func (svc *ServiceConfig) GetMaxConcurrency() int {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"slices"
 	"testing"
 )
 
@@ -166,6 +167,11 @@ func TestValidateEnvArgs(t *testing.T) {
 			service: "glm",
 			wantErr: true,
 		},
+		{
+			name:    "anthropic missing required env",
+			service: "anthropic",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -196,6 +202,23 @@ func TestValidateEnvArgs(t *testing.T) {
 	}
 }
 
+func TestGetServices(t *testing.T) {
+	svc := NewServiceConfig("fallback")
+	if svc.Type != "fallback" {
+		t.Errorf("Type = %q, want fallback", svc.Type)
+	}
+	want := []string{"glm", "siliconflow", "google"}
+	if got := svc.GetServices(); !slices.Equal(got, want) {
+		t.Errorf("GetServices() = %v, want %v", got, want)
+	}
+
+	t.Setenv("FALLBACK_SERVICES", "deepl, google")
+	want = []string{"deepl", "google"}
+	if got := svc.GetServices(); !slices.Equal(got, want) {
+		t.Errorf("GetServices() with env = %v, want %v", got, want)
+	}
+}
+
 func keysOf(m map[string]any) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -203,3 +226,26 @@ func keysOf(m map[string]any) []string {
 	}
 	return keys
 }
+
+func TestGetRoutes(t *testing.T) {
+	svc := NewServiceConfig("router")
+	if svc.Type != "router" {
+		t.Errorf("Type = %q, want router", svc.Type)
+	}
+	routes := svc.GetRoutes()
+	if len(routes) != 3 {
+		t.Fatalf("GetRoutes() = %v, want 3 routes", routes)
+	}
+	if !slices.Equal(routes[0].To, []string{"ja", "ko"}) || routes[0].Service != "glm" {
+		t.Errorf("routes[0] = %+v", routes[0])
+	}
+	if len(routes[2].To) != 0 || routes[2].Service != "google" {
+		t.Errorf("routes[2] = %+v, want catch-all google", routes[2])
+	}
+
+	t.Setenv("ROUTER_ROUTES", `[{"from":["zh"],"service":"deepl"},{"service":"google"}]`)
+	routes = svc.GetRoutes()
+	if len(routes) != 2 || !slices.Equal(routes[0].From, []string{"zh"}) || routes[0].Service != "deepl" {
+		t.Errorf("GetRoutes() with env = %+v", routes)
+	}
+}
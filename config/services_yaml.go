@@ -14,6 +14,8 @@ const (
 	kExtraBody      = "extra-body"
 	kRpm            = "rpm"
 	kMaxConcurrency = "max-concurrency"
+	kServices       = "services"
+	kRoutes         = "routes"
 )
 
 /* =========================
@@ -26,6 +28,15 @@ type ServiceYAML struct {
 	Rpm            int            `yaml:"rpm"`
 	MaxConcurrency int            `yaml:"max-concurrency"`
 	ExtraBody      map[string]any `yaml:"extra-body"`
+	Services       []string       `yaml:"services"`
+	Routes         []Route        `yaml:"routes"`
+}
+
+// Route 按目标语言to、检测到的源语言from选择服务，条件为空表示不限
+type Route struct {
+	To      []string `yaml:"to" json:"to"`
+	From    []string `yaml:"from" json:"from"`
+	Service string   `yaml:"service" json:"service"`
 }
 
 type ServicesYAML map[string]*ServiceYAML
@@ -60,13 +71,8 @@ func loadServicesYAML() ServicesYAML {
 
 func parseServiceYAML(m map[string]any) *ServiceYAML {
 	svc := &ServiceYAML{}
-	if arrOfAny, ok := m[kRequired].([]any); ok {
-		svc.Required = make([]string, 0, len(arrOfAny))
-		for _, a := range arrOfAny {
-			if s, ok := a.(string); ok {
-				svc.Required = append(svc.Required, s)
-			}
-		}
+	if v, ok := m[kRequired].([]any); ok {
+		svc.Required = toStrings(v)
 	}
 	if v, ok := m[kType].(string); ok {
 		svc.Type = v
@@ -80,9 +86,47 @@ func parseServiceYAML(m map[string]any) *ServiceYAML {
 	if v, ok := m[kExtraBody].(map[string]any); ok {
 		svc.ExtraBody = v
 	}
+	if v, ok := m[kServices].([]any); ok {
+		svc.Services = toStrings(v)
+	}
+	if v, ok := m[kRoutes].([]any); ok {
+		svc.Routes = parseRoutes(v)
+	}
 	return svc
 }
 
+func parseRoutes(arrOfAny []any) []Route {
+	routes := make([]Route, 0, len(arrOfAny))
+	for _, a := range arrOfAny {
+		m, ok := a.(map[string]any)
+		if !ok {
+			continue
+		}
+		var r Route
+		if v, ok := m["to"].([]any); ok {
+			r.To = toStrings(v)
+		}
+		if v, ok := m["from"].([]any); ok {
+			r.From = toStrings(v)
+		}
+		if v, ok := m["service"].(string); ok {
+			r.Service = v
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+func toStrings(arrOfAny []any) []string {
+	strs := make([]string, 0, len(arrOfAny))
+	for _, a := range arrOfAny {
+		if s, ok := a.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
 func (svc *ServiceYAML) copy() *ServiceYAML {
 	if svc == nil {
 		return nil
@@ -93,6 +137,8 @@ func (svc *ServiceYAML) copy() *ServiceYAML {
 		Rpm:            svc.Rpm,
 		MaxConcurrency: svc.MaxConcurrency,
 		Required:       append([]string(nil), svc.Required...),
+		Services:       append([]string(nil), svc.Services...),
+		Routes:         append([]Route(nil), svc.Routes...),
 	}
 
 	if svc.ExtraBody != nil {
@@ -127,6 +173,12 @@ func merge(base, override *ServiceYAML) *ServiceYAML {
 	if override.ExtraBody != nil {
 		merged.ExtraBody = maps.Clone(override.ExtraBody)
 	}
+	if len(override.Services) > 0 {
+		merged.Services = append([]string(nil), override.Services...)
+	}
+	if len(override.Routes) > 0 {
+		merged.Routes = append([]Route(nil), override.Routes...)
+	}
 	return merged
 }
 
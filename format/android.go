@@ -0,0 +1,303 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Java格式符（%s、%1$s、%.2f、%%、%n）和表示原样保留空白的双引号
+	androidProtectRe = regexp.MustCompile(`%(?:\d+\$)?[-#+ 0,(<]*\d*(?:\.\d+)?(?:[tT][a-zA-Z]|[bBhHsScCdoxXeEfgGaA%n])|"`)
+	// CDATA中的HTML标签
+	androidTagRe = regexp.MustCompile(`<[^<>]+>`)
+	// 译文中需要反斜杠转义的字符
+	androidEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`)
+)
+
+// 反斜杠转义后按原字符翻译的字符
+const androidLiteral = `'"\@?`
+
+// androidUnit 待翻译的<string>或<item>，CDATA中的内容只做Android转义
+type androidUnit struct {
+	originals []string
+	cdata     bool
+}
+
+type androidDoc struct {
+	parts []part // seg为units的下标
+	units []*androidUnit
+	texts []string
+}
+
+// androidItem <plurals>中的<item>
+type androidItem struct {
+	quantity string
+	start    int // <item>元素的范围
+	end      int
+	inner    [2]int
+}
+
+// ParseAndroid 解析Android的strings.xml，翻译<string>、<string-array>和<plurals>中的文字。
+// translatable="false"的资源不翻译，<xliff:g>、HTML标签、%1$s等格式符和\n等转义换成占位符。
+// 指定目标语言时，<plurals>按目标语言的复数类别生成<item>
+func ParseAndroid(data []byte, opts Options) (Document, error) {
+	d := &androidDoc{}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	var names []string // 所在元素
+	translatable := []bool{true}
+	var plurals []androidItem
+	inPlurals := false
+	last := 0 // data[last:]尚未写入parts
+
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing android strings: %w", err)
+		}
+		end := int(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			tr := translatable[len(translatable)-1] && xmlAttr(t.Attr, "translatable") != "false"
+			name := t.Name.Local
+			parent := ""
+			if len(names) > 0 {
+				parent = names[len(names)-1]
+			}
+			if name != "string" && (name != "item" || (parent != "string-array" && parent != "plurals")) {
+				names = append(names, name)
+				translatable = append(translatable, tr)
+				inPlurals = inPlurals || (name == "plurals" && tr)
+				continue
+			}
+
+			// <string>、<item>的内容整体处理
+			innerEnd, elemEnd, err := skipElement(dec)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing android strings: %w", err)
+			}
+			if !tr {
+				continue
+			}
+			if parent == "plurals" && inPlurals {
+				item := androidItem{xmlAttr(t.Attr, "quantity"), start, elemEnd, [2]int{end, innerEnd}}
+				plurals = append(plurals, item)
+				continue
+			}
+			d.addRaw(string(data[last:end]))
+			d.addValue(string(data[end:innerEnd]))
+			last = innerEnd
+
+		case xml.EndElement:
+			names = names[:len(names)-1]
+			translatable = translatable[:len(translatable)-1]
+			if t.Name.Local == "plurals" && inPlurals {
+				d.addPlurals(data, &last, plurals, opts.ToLang)
+				plurals, inPlurals = nil, false
+			}
+		}
+	}
+	d.addRaw(string(data[last:]))
+	return d, nil
+}
+
+// skipElement 读到当前元素的结束标签，返回结束标签的起点和终点
+func skipElement(dec *xml.Decoder) (int, int, error) {
+	depth := 1
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err != nil {
+			return 0, 0, err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth--; depth == 0 {
+				return start, int(dec.InputOffset()), nil
+			}
+		}
+	}
+}
+
+// addPlurals 指定目标语言时按其复数类别重新生成<item>，否则逐个翻译
+func (d *androidDoc) addPlurals(data []byte, last *int, items []androidItem, toLang string) {
+	if len(items) == 0 {
+		return
+	}
+	categories := pluralCategoriesFor(toLang)
+	if categories == nil {
+		for _, item := range items {
+			d.addRaw(string(data[*last:item.inner[0]]))
+			d.addValue(string(data[item.inner[0]:item.inner[1]]))
+			*last = item.inner[1]
+		}
+		return
+	}
+
+	quantities := make([]string, len(items))
+	for i, item := range items {
+		quantities[i] = item.quantity
+	}
+	indent := lineIndent(data, items[0].start)
+	d.addRaw(string(data[*last:items[0].start]))
+	for i, c := range categories {
+		if i > 0 {
+			d.addRaw(indent)
+		}
+		d.addRaw(`<item quantity="` + c + `">`)
+		inner := items[pluralSource(c, quantities)].inner
+		d.addValue(string(data[inner[0]:inner[1]]))
+		d.addRaw("</item>")
+	}
+	*last = items[len(items)-1].end
+}
+
+// addValue 翻译<string>、<item>的内容，首尾空白原样保留
+func (d *androidDoc) addValue(inner string) {
+	trimmed := strings.TrimSpace(inner)
+	start := strings.Index(inner, trimmed)
+	text, u := androidInline(trimmed)
+	if u == nil {
+		d.addRaw(inner)
+		return
+	}
+	d.addRaw(inner[:start])
+	d.parts = append(d.parts, part{seg: len(d.units)})
+	d.units = append(d.units, u)
+	d.texts = append(d.texts, text)
+	d.addRaw(inner[start+len(trimmed):])
+}
+
+// androidInline 提取资源值的文本，没有可翻译的文字或是对其他资源的引用（@string/name、?attr/name）时返回nil
+func androidInline(inner string) (string, *androidUnit) {
+	if strings.HasPrefix(inner, "@") || strings.HasPrefix(inner, "?") {
+		return "", nil
+	}
+	p := &protector{}
+	u := &androidUnit{}
+	if strings.HasPrefix(inner, "<![CDATA[") && strings.HasSuffix(inner, "]]>") && strings.Count(inner, "<![CDATA[") == 1 {
+		// 整个值是CDATA，其中的HTML标签换成占位符
+		u.cdata = true
+		p.keep("<![CDATA[")
+		content := inner[len("<![CDATA[") : len(inner)-len("]]>")]
+		last := 0
+		for _, loc := range androidTagRe.FindAllStringIndex(content, -1) {
+			p.escaped(content[last:loc[0]], androidProtectRe, androidLiteral)
+			p.keep(content[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		p.escaped(content[last:], androidProtectRe, androidLiteral)
+		p.keep("]]>")
+	} else if !androidMarkup(p, inner) {
+		return "", nil
+	}
+
+	text, originals := p.result()
+	if !hasLetter(placeholderRe.ReplaceAllString(text, "")) {
+		return "", nil
+	}
+	u.originals = originals
+	return text, u
+}
+
+// androidMarkup 处理含<b>、<xliff:g>等标签的资源值，<xliff:g>整个元素不翻译
+func androidMarkup(p *protector, inner string) bool {
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	dec.Strict = false
+	atomic := 0 // 在<xliff:g>中的嵌套深度
+	atomicStart := 0
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+		end := int(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if atomic > 0 {
+				atomic++
+			} else if t.Name.Space == "xliff" && t.Name.Local == "g" {
+				atomic, atomicStart = 1, start
+			} else {
+				p.keep(inner[start:end])
+			}
+		case xml.EndElement:
+			if atomic > 0 {
+				if atomic--; atomic == 0 {
+					p.keep(inner[atomicStart:end])
+				}
+			} else if start < end {
+				p.keep(inner[start:end])
+			}
+		case xml.CharData:
+			if atomic > 0 {
+				continue
+			}
+			if strings.HasPrefix(inner[start:end], "<![CDATA[") {
+				p.keep(inner[start:end])
+				continue
+			}
+			// 原文中形如占位符的文本也保护起来
+			text, last := string(t), 0
+			for _, loc := range placeholderRe.FindAllStringIndex(text, -1) {
+				p.escaped(text[last:loc[0]], androidProtectRe, androidLiteral)
+				p.keep(xmlEscaper.Replace(text[loc[0]:loc[1]]))
+				last = loc[1]
+			}
+			p.escaped(text[last:], androidProtectRe, androidLiteral)
+		default: // 注释
+			if atomic == 0 {
+				p.keep(inner[start:end])
+			}
+		}
+	}
+}
+
+func (d *androidDoc) addRaw(s string) {
+	d.parts = appendRaw(d.parts, s)
+}
+
+func (d *androidDoc) Texts() []string {
+	return d.texts
+}
+
+func (d *androidDoc) Render(w io.Writer, translated []string) error {
+	writer := bufio.NewWriter(w)
+	for _, p := range d.parts {
+		s := p.raw
+		if p.seg >= 0 {
+			u := d.units[p.seg]
+			escape := func(s string) string { return xmlEscaper.Replace(androidEscaper.Replace(s)) }
+			if u.cdata {
+				escape = androidEscaper.Replace
+			}
+			s = restoreEscaped(translated[p.seg], u.originals, escape)
+			// 开头的@、?表示引用其他资源
+			if strings.HasPrefix(s, "@") || strings.HasPrefix(s, "?") {
+				s = `\` + s
+			}
+		}
+		if _, err := writer.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,93 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const androidSrc = `<?xml version="1.0" encoding="utf-8"?>
+<resources xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">
+    <string name="app_name" translatable="false">MyApp</string>
+    <string name="hello">Hello, %1$s! You\'re <b>welcome</b>.</string>
+    <string name="download">Downloading <xliff:g id="file">%s</xliff:g>…\nPlease wait</string>
+    <string name="html"><![CDATA[Read the <a href="%1$s">terms</a>]]></string>
+    <string-array name="planets">
+        <item>Mercury</item>
+        <item>@string/app_name</item>
+    </string-array>
+    <plurals name="songs">
+        <item quantity="one">%d song</item>
+        <item quantity="other">%d songs</item>
+    </plurals>
+</resources>
+`
+
+func TestAndroid(t *testing.T) {
+	doc, err := ParseAndroid([]byte(androidSrc), Options{ToLang: "ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Hello, {ID_0}! You're {ID_1}welcome{ID_2}.",
+		"Downloading {ID_0}…{ID_1}Please wait",
+		"{ID_0}Read the {ID_1}terms{ID_2}",
+		"Mercury",
+		"{ID_0} song",
+		"{ID_0} songs",
+		"{ID_0} songs",
+		"{ID_0} songs",
+	}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{
+		"Привет, {ID_0}! Добро {ID_1}пожаловать{ID_2} & \"удачи\".",
+		"Загрузка {ID_0}…{ID_1}Подождите",
+		"{ID_0}Прочтите {ID_1}условия{ID_2}",
+		"Меркурий",
+		"{ID_0} песня",
+		"{ID_0} песни",
+		"{ID_0} песен",
+		"{ID_0} песни",
+	})
+	for _, s := range []string{
+		`<string name="app_name" translatable="false">MyApp</string>`,
+		`<string name="hello">Привет, %1$s! Добро <b>пожаловать</b> &amp; \"удачи\".</string>`,
+		`<string name="download">Загрузка <xliff:g id="file">%s</xliff:g>…\nПодождите</string>`,
+		`<string name="html"><![CDATA[Прочтите <a href="%1$s">условия</a>]]></string>`,
+		"<item>Меркурий</item>\n        <item>@string/app_name</item>",
+		"<item quantity=\"one\">%d песня</item>\n        <item quantity=\"few\">%d песни</item>\n" +
+			"        <item quantity=\"many\">%d песен</item>\n        <item quantity=\"other\">%d песни</item>\n    </plurals>",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestAndroid_Escape(t *testing.T) {
+	doc, err := ParseAndroid([]byte(`<resources><string name="a">\@home</string></resources>`), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"@home"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	if got := render(t, doc, []string{"@accueil d'abord"}); got != `<resources><string name="a">\@accueil d\'abord</string></resources>` {
+		t.Errorf("rendered %s", got)
+	}
+}
+
+func TestDetect_Android(t *testing.T) {
+	for name, want := range map[string]string{
+		"app/src/main/res/values/strings.xml":       "android",
+		"app/src/main/res/values-zh-rCN/arrays.xml": "android",
+		"layout/main.xml":                           Text,
+	} {
+		if got := Detect(name); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
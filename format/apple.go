@@ -0,0 +1,443 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// 机器翻译的译文状态
+const xcstringsState = "needs_review"
+
+var (
+	// 格式符：%@、%1$@、%lld、%.2f、%%，以及.stringsdict引用的%#@name@
+	appleProtectRe = regexp.MustCompile(`%#@\w+@|%(?:\d+\$)?[-+ #0']*(?:\d+|\*)?(?:\.(?:\d+|\*))?` +
+		`(?:hh|h|ll|l|q|L|z|t|j)?[@dDiuUxXoOfFeEgGaAcCsSp%]`)
+	// 译文中需要转义的字符
+	appleEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// 反斜杠转义后按原字符翻译的字符
+const appleLiteral = `"\'`
+
+// ParseApple 解析iOS、macOS的.strings文件和.xcstrings字符串目录（JSON格式，按内容区分）。
+// %@、%lld等格式符和\n等转义换成占位符
+func ParseApple(data []byte, opts Options) (Document, error) {
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		return parseXCStrings(data, opts)
+	}
+	return parseStrings(data)
+}
+
+// stringsDoc .strings文件，只替换"key" = "value";中的value
+type stringsDoc struct {
+	parts     []part // seg为originals的下标
+	originals [][]string
+	texts     []string
+	utf16     binary.ByteOrder // 原文为UTF-16时按原字节序输出
+}
+
+func parseStrings(data []byte) (Document, error) {
+	d := &stringsDoc{}
+	src := string(data)
+	if len(data) >= 2 && (data[0] == 0xFF && data[1] == 0xFE || data[0] == 0xFE && data[1] == 0xFF) {
+		d.utf16 = binary.LittleEndian
+		if data[0] == 0xFE {
+			d.utf16 = binary.BigEndian
+		}
+		src = decodeUTF16(data, d.utf16)
+	}
+
+	last := 0
+	expect := "key" // 依次为key、=、value、;
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "\uFEFF"):
+			i += len("\uFEFF")
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, stringsError(src, i, "unterminated comment")
+			}
+			i += 2 + end + 2
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			i += end
+		case c == '=' || c == ';':
+			if string(c) != expect {
+				return nil, stringsError(src, i, fmt.Sprintf("unexpected %q", c))
+			}
+			if c == '=' {
+				expect = "value"
+			} else {
+				expect = "key"
+			}
+			i++
+		default:
+			if expect != "key" && expect != "value" {
+				return nil, stringsError(src, i, fmt.Sprintf("expect %q", expect))
+			}
+			end, err := stringsToken(src, i)
+			if err != nil {
+				return nil, stringsError(src, i, err.Error())
+			}
+			if expect == "value" && c == '"' {
+				d.addValue(src, &last, i+1, end-1)
+			}
+			if expect == "key" {
+				expect = "="
+			} else {
+				expect = ";"
+			}
+			i = end
+		}
+	}
+	d.parts = appendRaw(d.parts, src[last:])
+	return d, nil
+}
+
+// stringsToken 返回从src[i]开始的字符串或不带引号的单词的终点
+func stringsToken(src string, i int) (int, error) {
+	if src[i] != '"' {
+		j := i
+		for j < len(src) && !strings.ContainsRune(" \t\r\n=;\"", rune(src[j])) {
+			j++
+		}
+		return j, nil
+	}
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated string")
+}
+
+func stringsError(src string, i int, msg string) error {
+	return fmt.Errorf("error parsing strings at line %d: %s", strings.Count(src[:i], "\n")+1, msg)
+}
+
+// addValue 翻译src[start:end]的字符串内容
+func (d *stringsDoc) addValue(src string, last *int, start, end int) {
+	p := &protector{}
+	p.escaped(src[start:end], appleProtectRe, appleLiteral)
+	text, originals := p.result()
+	if !hasLetter(placeholderRe.ReplaceAllString(text, "")) {
+		return
+	}
+	d.parts = appendRaw(d.parts, src[*last:start])
+	d.parts = append(d.parts, part{seg: len(d.texts)})
+	d.originals = append(d.originals, originals)
+	d.texts = append(d.texts, text)
+	*last = end
+}
+
+func (d *stringsDoc) Texts() []string {
+	return d.texts
+}
+
+func (d *stringsDoc) Render(w io.Writer, translated []string) error {
+	var sb strings.Builder
+	for _, p := range d.parts {
+		if p.seg >= 0 {
+			sb.WriteString(restoreEscaped(translated[p.seg], d.originals[p.seg], appleEscaper.Replace))
+		} else {
+			sb.WriteString(p.raw)
+		}
+	}
+	if d.utf16 != nil {
+		_, err := w.Write(encodeUTF16(sb.String(), d.utf16))
+		return err
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// decodeUTF16 解码UTF-16，BOM原样保留为U+FEFF
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func encodeUTF16(s string, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(s))
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(data[2*i:], u)
+	}
+	return data
+}
+
+// xcstringsUnit 待填写译文的stringUnit
+type xcstringsUnit struct {
+	obj       *jsonObject
+	originals []string
+}
+
+// xcstringsDoc .xcstrings字符串目录，为缺少目标语言的条目添加localizations
+type xcstringsDoc struct {
+	root     *jsonObject
+	units    []xcstringsUnit
+	texts    []string
+	finalEOL bool
+}
+
+func parseXCStrings(data []byte, opts Options) (Document, error) {
+	if opts.ToLang == "" {
+		return nil, errors.New("xcstrings requires a target language")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing xcstrings: %w", err)
+	}
+	root, ok := v.(*jsonObject)
+	if !ok {
+		return nil, errors.New("error parsing xcstrings: root is not an object")
+	}
+	d := &xcstringsDoc{root: root, finalEOL: bytes.HasSuffix(data, []byte("\n"))}
+
+	srcLang, _ := root.get("sourceLanguage").(string)
+	if srcLang == "" {
+		srcLang = "en"
+	}
+	strs, _ := root.get("strings").(*jsonObject)
+	if strs == nil {
+		return d, nil
+	}
+	for _, key := range strs.keys {
+		entry, _ := strs.values[key].(*jsonObject)
+		if entry == nil {
+			continue
+		}
+		if b, ok := entry.get("shouldTranslate").(bool); ok && !b {
+			continue
+		}
+		locs, _ := entry.get("localizations").(*jsonObject)
+		if locs != nil && locs.get(opts.ToLang) != nil {
+			continue
+		}
+
+		// 没有源语言的localization时，键就是原文
+		var source any
+		if locs != nil {
+			source = locs.get(srcLang)
+		}
+		if source == nil {
+			unit := &jsonObject{}
+			unit.set("state", "translated")
+			unit.set("value", key)
+			source = &jsonObject{}
+			source.(*jsonObject).set("stringUnit", unit)
+		}
+		n := len(d.texts)
+		target := d.localize(source, opts.ToLang)
+		if len(d.texts) == n {
+			continue
+		}
+		if locs == nil {
+			locs = &jsonObject{}
+			entry.insert("localizations", locs)
+		}
+		locs.insert(opts.ToLang, target)
+	}
+	return d, nil
+}
+
+// localize 复制源语言的localization作为译文：stringUnit换成待翻译的新对象，
+// 复数变体按目标语言的复数类别生成
+func (d *xcstringsDoc) localize(v any, toLang string) any {
+	obj, ok := v.(*jsonObject)
+	if !ok {
+		return v
+	}
+	result := &jsonObject{}
+	for _, k := range obj.keys {
+		child := obj.values[k]
+		switch c, _ := child.(*jsonObject); {
+		case k == "stringUnit" && c != nil:
+			result.set(k, d.stringUnit(c))
+		case k == "plural" && c != nil && len(c.keys) > 0:
+			categories := pluralCategoriesFor(toLang)
+			plural := &jsonObject{}
+			for _, cat := range categories {
+				plural.set(cat, d.localize(c.values[c.keys[pluralSource(cat, c.keys)]], toLang))
+			}
+			result.set(k, plural)
+		default:
+			result.set(k, d.localize(child, toLang))
+		}
+	}
+	return result
+}
+
+func (d *xcstringsDoc) stringUnit(src *jsonObject) *jsonObject {
+	value, _ := src.get("value").(string)
+	unit := &jsonObject{}
+	unit.set("state", xcstringsState)
+	unit.set("value", value)
+
+	text, originals := protectText(value, appleProtectRe)
+	if hasLetter(placeholderRe.ReplaceAllString(text, "")) {
+		d.units = append(d.units, xcstringsUnit{unit, originals})
+		d.texts = append(d.texts, text)
+	}
+	return unit
+}
+
+func (d *xcstringsDoc) Texts() []string {
+	return d.texts
+}
+
+func (d *xcstringsDoc) Render(w io.Writer, translated []string) error {
+	for i, u := range d.units {
+		u.obj.set("value", restoreText(translated[i], u.originals))
+	}
+	writer := bufio.NewWriter(w)
+	writeXCStrings(writer, d.root, "")
+	if d.finalEOL {
+		writer.WriteString("\n")
+	}
+	return writer.Flush()
+}
+
+// jsonObject 保持键顺序的JSON对象
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *jsonObject) get(key string) any {
+	return o.values[key]
+}
+
+// set 设置键的值，新键追加在最后
+func (o *jsonObject) set(key string, v any) {
+	if o.values == nil {
+		o.values = map[string]any{}
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// insert 设置键的值，新键按字母序插入，与Xcode的输出一致
+func (o *jsonObject) insert(key string, v any) {
+	if _, ok := o.values[key]; ok {
+		o.values[key] = v
+		return
+	}
+	o.set(key, v)
+	i := len(o.keys) - 1
+	for ; i > 0 && o.keys[i-1] > key; i-- {
+		o.keys[i] = o.keys[i-1]
+	}
+	o.keys[i] = key
+}
+
+// decodeJSONValue 解码JSON值，对象解码为*jsonObject
+func decodeJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := &jsonObject{values: map[string]any{}}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(tok.(string), v)
+		}
+		_, err = dec.Token()
+		return obj, err
+	case '[':
+		var arr []any
+		for dec.More() {
+			v, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("unexpected %v", delim)
+}
+
+// writeXCStrings 按Xcode的格式输出：两个空格缩进，冒号前后有空格，空对象为{\n\n}
+func writeXCStrings(w *bufio.Writer, v any, indent string) {
+	inner := indent + "  "
+	switch v := v.(type) {
+	case *jsonObject:
+		if len(v.keys) == 0 {
+			w.WriteString("{\n\n" + indent + "}")
+			return
+		}
+		w.WriteString("{\n")
+		for i, k := range v.keys {
+			w.WriteString(inner + jsonString(k) + " : ")
+			writeXCStrings(w, v.values[k], inner)
+			if i < len(v.keys)-1 {
+				w.WriteString(",")
+			}
+			w.WriteString("\n")
+		}
+		w.WriteString(indent + "}")
+	case []any:
+		if len(v) == 0 {
+			w.WriteString("[\n\n" + indent + "]")
+			return
+		}
+		w.WriteString("[\n")
+		for i, e := range v {
+			w.WriteString(inner)
+			writeXCStrings(w, e, inner)
+			if i < len(v)-1 {
+				w.WriteString(",")
+			}
+			w.WriteString("\n")
+		}
+		w.WriteString(indent + "]")
+	case string:
+		w.WriteString(jsonString(v))
+	case json.Number:
+		w.WriteString(v.String())
+	case bool:
+		fmt.Fprint(w, v)
+	case nil:
+		w.WriteString("null")
+	}
+}
@@ -0,0 +1,158 @@
+package format
+
+import (
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppleStrings(t *testing.T) {
+	src := `/* Greeting on the home screen */
+"greeting" = "Hello, %@!";
+"items" = "%1$lld items in \"%2$@\"";
+// Untranslated
+"version" = "%d.%d";
+menu_title = "Main Menu";
+`
+	doc, err := ParseApple([]byte(src), Options{ToLang: "fr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Hello, {ID_0}!", `{ID_0} items in "{ID_1}"`, "Main Menu"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{"Bonjour, {ID_0} !", "{ID_0} éléments dans « {ID_1} »\nvoilà", `Menu "principal"`})
+	wantOut := `/* Greeting on the home screen */
+"greeting" = "Bonjour, %@ !";
+"items" = "%1$lld éléments dans « %2$@ »\nvoilà";
+// Untranslated
+"version" = "%d.%d";
+menu_title = "Menu \"principal\"";
+`
+	if got != wantOut {
+		t.Errorf("rendered:\n%s\nwant:\n%s", got, wantOut)
+	}
+}
+
+func TestAppleStrings_UTF16(t *testing.T) {
+	src := encodeUTF16("\uFEFF\"a\" = \"Open\";\n", binary.LittleEndian)
+	doc, err := ParseApple(src, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"Open"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	got := render(t, doc, []string{"Öffnen"})
+	if want := string(encodeUTF16("\uFEFF\"a\" = \"Öffnen\";\n", binary.LittleEndian)); got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+func TestAppleStrings_Error(t *testing.T) {
+	if _, err := ParseApple([]byte("\"a\" = \"b\"\n\"c\" = \"d\";"), Options{}); err == nil {
+		t.Error("expect error for missing semicolon")
+	}
+}
+
+const xcstringsSrc = `{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "%lld files" : {
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "plural" : {
+              "one" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld file"
+                }
+              },
+              "other" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld files"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "Done" : {
+      "localizations" : {
+        "de" : {
+          "stringUnit" : {
+            "state" : "translated",
+            "value" : "Fertig"
+          }
+        }
+      }
+    },
+    "Hello %@" : {
+      "comment" : "Greeting"
+    },
+    "MyApp" : {
+      "shouldTranslate" : false
+    }
+  },
+  "version" : "1.0"
+}
+`
+
+func TestXCStrings(t *testing.T) {
+	doc, err := ParseApple([]byte(xcstringsSrc), Options{ToLang: "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"{ID_0} file", "{ID_0} files", "Hello {ID_0}"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{"{ID_0} Datei", "{ID_0} Dateien", "Hallo {ID_0}"})
+	for _, s := range []string{
+		`"de" : {
+          "variations" : {
+            "plural" : {
+              "one" : {
+                "stringUnit" : {
+                  "state" : "needs_review",
+                  "value" : "%lld Datei"
+                }
+              },
+              "other" : {
+                "stringUnit" : {
+                  "state" : "needs_review",
+                  "value" : "%lld Dateien"
+                }
+              }
+            }
+          }
+        },
+        "en" : {`,
+		`"Hello %@" : {
+      "comment" : "Greeting",
+      "localizations" : {
+        "de" : {
+          "stringUnit" : {
+            "state" : "needs_review",
+            "value" : "Hallo %@"
+          }
+        }
+      }
+    },`,
+		`"MyApp" : {
+      "shouldTranslate" : false
+    }`,
+		"\"version\" : \"1.0\"\n}\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
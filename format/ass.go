@@ -0,0 +1,69 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 绘图模式的对白是矢量图形，不翻译
+var assDrawingRe = regexp.MustCompile(`\\p[1-9]`)
+
+// [Events]未声明Format时的默认字段
+var assDefaultFormat = []string{"Layer", "Start", "End", "Style", "Name", "MarginL", "MarginR", "MarginV", "Effect", "Text"}
+
+// ParseASS 解析ASS/SSA字幕，只翻译[Events]中Dialogue行的Text字段，其余原样保留
+func ParseASS(data []byte, opts Options) (Document, error) {
+	lines, eol, finalEOL := splitLines(data)
+	d := &subtitleDoc{}
+	inEvents := false
+	fields := assDefaultFormat
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inEvents = strings.EqualFold(trimmed, "[Events]")
+			d.addRaw(line + eol)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !inEvents || !ok {
+			d.addRaw(line + eol)
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "Format":
+			fields = strings.Split(value, ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+			d.addRaw(line + eol)
+		case "Dialogue":
+			// Text是最后一个字段，其中可能含逗号
+			values := strings.SplitN(value, ",", len(fields))
+			text := values[len(values)-1]
+			if len(values) < len(fields) || assDrawingRe.MatchString(text) {
+				d.addRaw(line + eol)
+				continue
+			}
+			d.addRaw(line[:len(line)-len(text)])
+			d.addCue([]string{text}, "", assField(fields, values, "Style")+","+assField(fields, values, "Name"))
+			d.addRaw(eol)
+		default:
+			d.addRaw(line + eol)
+		}
+	}
+	if !finalEOL {
+		trimFinalEOL(d.parts, eol)
+	}
+	d.group()
+	return d, nil
+}
+
+func assField(fields, values []string, name string) string {
+	for i, f := range fields {
+		if strings.EqualFold(f, name) && i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+	}
+	return ""
+}
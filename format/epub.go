@@ -0,0 +1,276 @@
+package format
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// epubContainer META-INF/container.xml，指向OPF文件
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage OPF文件中的清单和阅读顺序
+type epubPackage struct {
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Itemrefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// epubChapter 一个要翻译的文件，片段为epubDoc.texts[offset:offset+len(doc.Texts())]
+type epubChapter struct {
+	name   string
+	doc    Document
+	offset int
+}
+
+type epubDoc struct {
+	zip      *zip.Reader
+	chapters []*epubChapter
+	texts    []string
+	opfName  string
+	opf      []byte // 改过dc:language的OPF，没改时为nil
+}
+
+// ParseEPUB 解析EPUB电子书，按spine的顺序翻译各章节的XHTML，以及目录（nav、toc.ncx）。
+// 章节按HTML翻译，opts.Bilingual时译文段落跟在原文之后；否则将OPF中的dc:language改为目标语言
+func ParseEPUB(data []byte, opts Options) (Document, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error opening epub: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("error reading epub: missing %s", name)
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		return content, nil
+	}
+
+	content, err := read("META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+	var container epubContainer
+	if err := xml.Unmarshal(content, &container); err != nil {
+		return nil, fmt.Errorf("error parsing container.xml: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("error parsing container.xml: no rootfile")
+	}
+	opfName := container.Rootfiles[0].FullPath
+	opf, err := read(opfName)
+	if err != nil {
+		return nil, err
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", opfName, err)
+	}
+
+	d := &epubDoc{zip: r}
+	// href相对于OPF所在目录，可能经过URL编码
+	hrefs := map[string]string{}
+	for _, item := range pkg.Manifest {
+		href := item.Href
+		if s, err := url.PathUnescape(href); err == nil {
+			href = s
+		}
+		hrefs[item.ID] = path.Join(path.Dir(opfName), href)
+	}
+	added := map[string]bool{}
+	add := func(id string, parse Parser) error {
+		name, ok := hrefs[id]
+		if !ok || added[name] {
+			return nil
+		}
+		added[name] = true
+		content, err := read(name)
+		if err != nil {
+			return err
+		}
+		doc, err := parse(content, opts)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %w", name, err)
+		}
+		if texts := doc.Texts(); len(texts) > 0 {
+			d.chapters = append(d.chapters, &epubChapter{name: name, doc: doc, offset: len(d.texts)})
+			d.texts = append(d.texts, texts...)
+		}
+		return nil
+	}
+
+	mediaTypes := map[string]string{}
+	for _, item := range pkg.Manifest {
+		mediaTypes[item.ID] = item.MediaType
+	}
+	for _, ref := range pkg.Spine.Itemrefs {
+		if t := mediaTypes[ref.IDRef]; t == "application/xhtml+xml" || t == "text/html" {
+			if err := add(ref.IDRef, ParseHTML); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, item := range pkg.Manifest {
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			if err := add(item.ID, ParseHTML); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if pkg.Spine.Toc != "" && mediaTypes[pkg.Spine.Toc] == "application/x-dtbncx+xml" {
+		if err := add(pkg.Spine.Toc, parseNCX); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.Bilingual && opts.ToLang != "" {
+		d.opfName, d.opf = opfName, setDCLanguage(opf, opts.ToLang)
+	}
+	return d, nil
+}
+
+// setDCLanguage 将第一个dc:language的内容改为lang，没有dc:language时返回nil
+func setDCLanguage(opf []byte, lang string) []byte {
+	dec := xml.NewDecoder(bytes.NewReader(opf))
+	dec.Strict = false
+	inner := -1
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err != nil {
+			return nil
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == "dc" && t.Name.Local == "language" {
+				inner = int(dec.InputOffset())
+			}
+		case xml.EndElement:
+			if inner >= 0 && t.Name.Local == "language" {
+				var buf bytes.Buffer
+				buf.Write(opf[:inner])
+				buf.WriteString(xmlEscaper.Replace(lang))
+				buf.Write(opf[start:])
+				return buf.Bytes()
+			}
+		}
+	}
+}
+
+func (d *epubDoc) Texts() []string {
+	return d.texts
+}
+
+// Chapters 每个文件为一章
+func (d *epubDoc) Chapters() []int {
+	counts := make([]int, len(d.chapters))
+	for i, c := range d.chapters {
+		counts[i] = len(c.doc.Texts())
+	}
+	return counts
+}
+
+func (d *epubDoc) Render(w io.Writer, translated []string) error {
+	replaced := map[string][]byte{}
+	for _, c := range d.chapters {
+		var buf bytes.Buffer
+		if err := c.doc.Render(&buf, translated[c.offset:c.offset+len(c.doc.Texts())]); err != nil {
+			return fmt.Errorf("error rendering %s: %w", c.name, err)
+		}
+		replaced[c.name] = buf.Bytes()
+	}
+	if d.opf != nil {
+		replaced[d.opfName] = d.opf
+	}
+	return rewriteZip(w, d.zip, replaced)
+}
+
+// ncxDoc EPUB 2的目录toc.ncx，翻译<text>中的标题
+type ncxDoc struct {
+	parts []part
+	texts []string
+}
+
+func parseNCX(data []byte, opts Options) (Document, error) {
+	d := &ncxDoc{}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	last, inner := 0, -1
+	var sb strings.Builder
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			inner = -1
+			if t.Name.Local == "text" {
+				inner = int(dec.InputOffset())
+				sb.Reset()
+			}
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			if inner >= 0 && t.Name.Local == "text" && hasLetter(sb.String()) {
+				d.parts = appendRaw(d.parts, string(data[last:inner]))
+				d.parts = append(d.parts, part{seg: len(d.texts)})
+				d.texts = append(d.texts, strings.TrimSpace(sb.String()))
+				last = start
+			}
+			inner = -1
+		}
+	}
+	d.parts = appendRaw(d.parts, string(data[last:]))
+	return d, nil
+}
+
+func (d *ncxDoc) Texts() []string {
+	return d.texts
+}
+
+func (d *ncxDoc) Render(w io.Writer, translated []string) error {
+	writer := bufio.NewWriter(w)
+	for _, p := range d.parts {
+		s := p.raw
+		if p.seg >= 0 {
+			s = xmlEscaper.Replace(translated[p.seg])
+		}
+		if _, err := writer.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,98 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func makeEPUB(t *testing.T) []byte {
+	chapter := func(title, body string) string {
+		return `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="en">
+<head><title>` + title + `</title></head>
+<body>` + body + `</body>
+</html>`
+	}
+	return makeZip(t,
+		"mimetype", "application/epub+zip",
+		"META-INF/container.xml", `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf", `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Book</dc:title><dc:language>en</dc:language></metadata>
+  <manifest>
+    <item id="c2" href="text/ch%202.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+  </manifest>
+  <spine toc="ncx"><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+		"OEBPS/text/ch1.xhtml", chapter("One", `<h1 id="c1">Chapter One</h1><p>It was a <em>dark</em> night.<a id="p1"/></p>`),
+		"OEBPS/text/ch 2.xhtml", chapter("Two", `<p>The end.</p>`),
+		"OEBPS/style.css", `p { margin: 0 }`,
+		"OEBPS/toc.ncx", `<ncx><navMap><navPoint><navLabel><text>Chapter One</text></navLabel></navPoint></navMap></ncx>`,
+	)
+}
+
+func TestEPUB(t *testing.T) {
+	doc, err := ParseEPUB(makeEPUB(t), Options{ToLang: "zh-CN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"One", "Chapter One", "It was a {ID_0}dark{ID_1} night.{ID_2}", "Two", "The end.", "Chapter One"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	if got := doc.(Chaptered).Chapters(); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("Chapters() = %v", got)
+	}
+
+	names, files := unzip(t, render(t, doc, []string{"一", "第一章", "那是一个{ID_0}黑暗{ID_1}的夜晚。{ID_2}", "二", "完。", "第一章"}))
+	if names[0] != "mimetype" || files["mimetype"] != "application/epub+zip" {
+		t.Errorf("mimetype should stay first: %q", names)
+	}
+	ch1 := files["OEBPS/text/ch1.xhtml"]
+	for _, s := range []string{
+		`<?xml version="1.0" encoding="utf-8"?>` + "\n<!DOCTYPE html>",
+		`<h1 id="c1">第一章</h1><p>那是一个<em>黑暗</em>的夜晚。<a id="p1"></a></p>`,
+	} {
+		if !strings.Contains(ch1, s) {
+			t.Errorf("ch1.xhtml missing %q:\n%s", s, ch1)
+		}
+	}
+	if !strings.Contains(files["OEBPS/text/ch 2.xhtml"], "<p>完。</p>") {
+		t.Errorf("ch 2.xhtml = %s", files["OEBPS/text/ch 2.xhtml"])
+	}
+	if !strings.Contains(files["OEBPS/content.opf"], "<dc:language>zh-CN</dc:language>") {
+		t.Errorf("content.opf = %s", files["OEBPS/content.opf"])
+	}
+	if files["OEBPS/toc.ncx"] != `<ncx><navMap><navPoint><navLabel><text>第一章</text></navLabel></navPoint></navMap></ncx>` {
+		t.Errorf("toc.ncx = %s", files["OEBPS/toc.ncx"])
+	}
+}
+
+func TestEPUB_Bilingual(t *testing.T) {
+	doc, err := ParseEPUB(makeEPUB(t), Options{ToLang: "zh-CN", Bilingual: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, files := unzip(t, render(t, doc, []string{"一", "第一章", "那是一个{ID_0}黑暗{ID_1}的夜晚。{ID_2}", "二", "完。", "第一章"}))
+	ch1 := files["OEBPS/text/ch1.xhtml"]
+	for _, s := range []string{
+		"<title>一</title>",
+		`<h1 id="c1">Chapter One</h1><h1 lang="zh-CN">第一章</h1>`,
+		`<p>It was a <em>dark</em> night.<a id="p1"></a></p><p lang="zh-CN">那是一个<em>黑暗</em>的夜晚。<a id="p1"></a></p>`,
+	} {
+		if !strings.Contains(ch1, s) {
+			t.Errorf("ch1.xhtml missing %q:\n%s", s, ch1)
+		}
+	}
+	if !strings.Contains(files["OEBPS/content.opf"], "<dc:language>en</dc:language>") {
+		t.Errorf("bilingual book should keep dc:language: %s", files["OEBPS/content.opf"])
+	}
+}
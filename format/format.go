@@ -0,0 +1,120 @@
+// Package format 将不同格式的文档拆成待翻译的文本片段，翻译后按原有结构写回
+package format
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Text 纯文本按行翻译，由调用方直接处理
+const Text = "text"
+
+// Document 解析后的文档：Texts为待翻译的片段，Render用同样数量的译文按原有结构输出
+type Document interface {
+	Texts() []string
+	Render(w io.Writer, translated []string) error
+}
+
+// Chaptered 分章节的文档，调用方可以逐章翻译并保存进度
+type Chaptered interface {
+	Document
+	// Chapters 返回各章节的片段数，依次对应Texts中连续的片段
+	Chapters() []int
+}
+
+// Reusable 文本片段与目标语言无关、渲染时不修改文档的格式，解析一次后可以并发渲染成多种目标语言。
+// RenderLang按toLang输出语言标记（如XLIFF的trgLang），代替解析时的Options.ToLang
+type Reusable interface {
+	Document
+	RenderLang(w io.Writer, translated []string, toLang string) error
+}
+
+// Options 解析文档时与翻译相关的选项
+type Options struct {
+	ToLang string   // 目标语言，部分格式需要据此生成译文的结构，如PO的复数形式
+	Keys   []string // JSON、YAML资源文件中要翻译的键，如$.home.*、errors.**，为空时全部翻译
+	// 双语输出，保留原文，译文段落跟在原文之后（HTML、EPUB）
+	Bilingual bool
+}
+
+type Parser func(data []byte, opts Options) (Document, error)
+
+var parsers = map[string]Parser{
+	"markdown": ParseMarkdown,
+	"html":     ParseHTML,
+	"srt":      ParseSRT,
+	"vtt":      ParseVTT,
+	"ass":      ParseASS,
+	"po":       ParsePO,
+	"xliff":    ParseXLIFF,
+	"json":     ParseJSON,
+	"yaml":     ParseYAML,
+	"android":  ParseAndroid,
+	"apple":    ParseApple,
+	"docx":     ParseDOCX,
+	"pptx":     ParsePPTX,
+	"xlsx":     ParseXLSX,
+	"epub":     ParseEPUB,
+}
+
+// 按文件扩展名推断格式
+var extensions = map[string]string{
+	".md":        "markdown",
+	".markdown":  "markdown",
+	".mdx":       "markdown",
+	".html":      "html",
+	".htm":       "html",
+	".xhtml":     "html",
+	".srt":       "srt",
+	".vtt":       "vtt",
+	".ass":       "ass",
+	".ssa":       "ass",
+	".po":        "po",
+	".pot":       "po",
+	".xliff":     "xliff",
+	".xlf":       "xliff",
+	".json":      "json",
+	".yaml":      "yaml",
+	".yml":       "yaml",
+	".strings":   "apple",
+	".xcstrings": "apple",
+	".docx":      "docx",
+	".pptx":      "pptx",
+	".xlsx":      "xlsx",
+	".epub":      "epub",
+}
+
+// Names 返回支持的格式名
+func Names() []string {
+	names := []string{Text}
+	for name := range parsers {
+		names = append(names, name)
+	}
+	slices.Sort(names[1:])
+	return names
+}
+
+// Detect 按文件扩展名推断格式，无法推断时返回Text。
+// Android资源目录（values、values-zh-rCN等）下的.xml文件为android格式
+func Detect(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".xml" && strings.HasPrefix(filepath.Base(filepath.Dir(filename)), "values") {
+		return "android"
+	}
+	if name, ok := extensions[ext]; ok {
+		return name
+	}
+	return Text
+}
+
+// Parse 按格式name解析文档
+func Parse(name string, data []byte, opts Options) (Document, error) {
+	parser, ok := parsers[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %q, expect one of: %s", name, strings.Join(Names(), ", "))
+	}
+	return parser(data, opts)
+}
@@ -0,0 +1,63 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReusable_RenderLang(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		src  string
+	}{
+		{"markdown", "# Title\n\nHello world.\n"},
+		{"srt", "1\n00:00:01,000 --> 00:00:02,000\nHello world.\n"},
+		{"xliff", `<xliff version="1.2"><file source-language="en"><body><trans-unit id="1"><source>Hello</source></trans-unit></body></file></xliff>`},
+		{"xliff", `<xliff version="2.0" srcLang="en"><file id="f"><unit id="1"><segment><source>Hello</source></segment></unit></file></xliff>`},
+		{"json", `{"en": {"hello": "Hello"}}`},
+	} {
+		// 解析一次后渲染成de，应与按de解析的结果相同
+		doc, err := Parse(tt.name, []byte(tt.src), Options{ToLang: "fr"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, ok := doc.(Reusable)
+		if !ok {
+			t.Fatalf("%s: parsed document is not Reusable", tt.name)
+		}
+		translated := make([]string, len(doc.Texts()))
+		for i, s := range doc.Texts() {
+			translated[i] = "<" + s + ">"
+		}
+		var got bytes.Buffer
+		if err := r.RenderLang(&got, translated, "de"); err != nil {
+			t.Fatal(err)
+		}
+
+		doc, err = Parse(tt.name, []byte(tt.src), Options{ToLang: "de"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := render(t, doc, translated); got.String() != want {
+			t.Errorf("%s: RenderLang(de) = %q, want %q", tt.name, got.String(), want)
+		}
+	}
+}
+
+func TestReusable_LangDependentFormats(t *testing.T) {
+	// 复数形式随目标语言变化，或渲染时修改文档树，不能共用解析结果
+	for name, src := range map[string]string{
+		"po":      "msgid \"\"\nmsgstr \"\"\n\nmsgid \"Hello\"\nmsgstr \"\"\n",
+		"android": `<resources><string name="hello">Hello</string></resources>`,
+		"html":    "<p>Hello</p>",
+		"yaml":    "hello: Hello\n",
+	} {
+		doc, err := Parse(name, []byte(src), Options{ToLang: "fr"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := doc.(Reusable); ok {
+			t.Errorf("%s: document should not be Reusable", name)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/smilingpoplar/translate/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// 含这些标记时按完整文档解析，否则按body内的片段解析
+	htmlDocRe = regexp.MustCompile(`(?i)<!doctype|<html[\s>]|<head[\s>]|<body[\s>]`)
+
+	// 不翻译内容的元素
+	htmlSkipTags = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Code: true, atom.Pre: true,
+		atom.Kbd: true, atom.Samp: true, atom.Textarea: true, atom.Svg: true,
+		atom.Math: true, atom.Template: true,
+	}
+
+	// 行内元素，与相邻文本合并为一个片段翻译
+	htmlInlineTags = map[atom.Atom]bool{
+		atom.A: true, atom.Abbr: true, atom.B: true, atom.Bdi: true, atom.Bdo: true,
+		atom.Br: true, atom.Button: true, atom.Cite: true, atom.Code: true, atom.Data: true,
+		atom.Del: true, atom.Dfn: true, atom.Em: true, atom.Font: true, atom.I: true,
+		atom.Img: true, atom.Input: true, atom.Ins: true, atom.Kbd: true, atom.Label: true,
+		atom.Mark: true, atom.Q: true, atom.S: true, atom.Samp: true, atom.Small: true,
+		atom.Span: true, atom.Strong: true, atom.Sub: true, atom.Sup: true, atom.Time: true,
+		atom.U: true, atom.Var: true, atom.Wbr: true,
+	}
+
+	// 需要翻译的属性
+	htmlAttrs = map[string]bool{"title": true, "alt": true, "placeholder": true}
+
+	// 双语输出时复制整个元素放译文的块级元素
+	htmlBilingualTags = map[atom.Atom]bool{
+		atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+		atom.H6: true, atom.Li: true, atom.Dt: true, atom.Dd: true, atom.Div: true, atom.Blockquote: true,
+	}
+
+	// XHTML（如EPUB的章节）的XML声明
+	xmlDeclRe = regexp.MustCompile(`^\s*<\?xml[^>]*\?>\s*`)
+	// XHTML的自闭合标签，html解析器只认空元素的
+	selfClosingRe = regexp.MustCompile(`<([A-Za-z][\w:.-]*)(\s[^<>]*?)?\s*/>`)
+	htmlVoidTags  = map[atom.Atom]bool{
+		atom.Area: true, atom.Base: true, atom.Br: true, atom.Col: true, atom.Embed: true,
+		atom.Hr: true, atom.Img: true, atom.Input: true, atom.Link: true, atom.Meta: true,
+		atom.Param: true, atom.Source: true, atom.Track: true, atom.Wbr: true,
+	}
+)
+
+// htmlSegment 待翻译的片段：属性值，或一段连续的行内内容
+type htmlSegment struct {
+	attr *html.Attribute
+
+	parent *html.Node
+	nodes  []*html.Node    // 行内内容的节点，翻译后整体替换
+	tags   []func() string // 各占位符对应的标签，输出时才生成以带上已翻译的属性
+	lead   string          // 首尾空白，原样保留
+	trail  string
+}
+
+type htmlDoc struct {
+	root      *html.Node   // 完整文档
+	nodes     []*html.Node // 片段的顶层节点
+	texts     []string
+	segments  []*htmlSegment
+	xmlDecl   string // XHTML的XML声明，输出时写回
+	bilingual bool
+	lang      string
+}
+
+// ParseHTML 提取文本节点和title、alt、placeholder属性，相邻的行内元素用占位符表示标签后合并翻译。
+// script、style、code等元素以及translate="no"、class="notranslate"的元素不翻译。
+// opts.Bilingual时保留原文，译文跟在原文段落之后
+func ParseHTML(data []byte, opts Options) (Document, error) {
+	d := &htmlDoc{bilingual: opts.Bilingual, lang: opts.ToLang}
+	if m := xmlDeclRe.Find(data); m != nil {
+		d.xmlDecl = strings.TrimSpace(string(m))
+		data = expandSelfClosing(data[len(m):])
+	}
+	if htmlDocRe.Match(data) {
+		root, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing html: %w", err)
+		}
+		d.root = root
+		d.walk(root)
+		return d, nil
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(bytes.NewReader(data), body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing html: %w", err)
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	d.root = body
+	d.nodes = nodes
+	d.walk(body)
+	return d, nil
+}
+
+func (d *htmlDoc) walk(n *html.Node) {
+	if n.Type == html.ElementNode {
+		if isNoTranslate(n) || htmlSkipTags[n.DataAtom] {
+			return
+		}
+		d.addAttrs(n)
+	}
+
+	// 子节点中连续的行内内容合并为一个片段，其余子节点递归处理
+	var run []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isInline(c) {
+			run = append(run, c)
+			continue
+		}
+		d.addRun(n, run)
+		run = nil
+		d.walk(c)
+	}
+	d.addRun(n, run)
+}
+
+func (d *htmlDoc) addAttrs(n *html.Node) {
+	for i := range n.Attr {
+		a := &n.Attr[i]
+		if a.Namespace == "" && htmlAttrs[a.Key] && hasLetter(a.Val) {
+			d.segments = append(d.segments, &htmlSegment{attr: a})
+			d.texts = append(d.texts, strings.TrimSpace(a.Val))
+		}
+	}
+}
+
+func (d *htmlDoc) addRun(parent *html.Node, run []*html.Node) {
+	if len(run) == 0 {
+		return
+	}
+	seg := &htmlSegment{parent: parent, nodes: run}
+	var sb strings.Builder
+	for _, n := range run {
+		d.writeInline(&sb, seg, n)
+	}
+
+	text := sb.String()
+	trimmed := strings.TrimSpace(text)
+	if !hasLetter(placeholderRe.ReplaceAllString(trimmed, "")) {
+		return
+	}
+	start := strings.Index(text, trimmed)
+	seg.lead, seg.trail = text[:start], text[start+len(trimmed):]
+	d.segments = append(d.segments, seg)
+	d.texts = append(d.texts, collapseSpace(trimmed))
+}
+
+// writeInline 写入行内节点的文本，标签换成占位符，不翻译的元素整体换成一个占位符
+func (d *htmlDoc) writeInline(sb *strings.Builder, seg *htmlSegment, n *html.Node) {
+	placeholder := func(tag func() string) {
+		sb.WriteString(util.GeneratePlaceholder(len(seg.tags)))
+		seg.tags = append(seg.tags, tag)
+	}
+
+	switch n.Type {
+	case html.TextNode:
+		// 原文中形如占位符的文本也当作标签保护起来
+		text, last := n.Data, 0
+		for _, loc := range placeholderRe.FindAllStringIndex(text, -1) {
+			sb.WriteString(text[last:loc[0]])
+			escaped := html.EscapeString(text[loc[0]:loc[1]])
+			placeholder(func() string { return escaped })
+			last = loc[1]
+		}
+		sb.WriteString(text[last:])
+	case html.ElementNode:
+		if isNoTranslate(n) {
+			placeholder(func() string { return renderNode(n) })
+			return
+		}
+		if htmlSkipTags[n.DataAtom] {
+			placeholder(func() string { return renderNode(n) })
+			return
+		}
+		d.addAttrs(n)
+		if n.FirstChild == nil {
+			placeholder(func() string { return renderNode(n) })
+			return
+		}
+		placeholder(func() string { return openTag(n) })
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			d.writeInline(sb, seg, c)
+		}
+		placeholder(func() string { return "</" + n.Data + ">" })
+	case html.CommentNode:
+		placeholder(func() string { return renderNode(n) })
+	}
+}
+
+func (d *htmlDoc) Texts() []string {
+	return d.texts
+}
+
+func (d *htmlDoc) Render(w io.Writer, translated []string) error {
+	// 先写回属性，行内元素的占位符输出时会带上已翻译的属性
+	for i, seg := range d.segments {
+		if seg.attr != nil {
+			seg.attr.Val = html.UnescapeString(translated[i])
+		}
+	}
+	for i, seg := range d.segments {
+		if seg.attr == nil {
+			replace := seg.replace
+			if d.bilingual {
+				replace = func(translated string) error { return seg.appendTranslation(translated, d.lang) }
+			}
+			if err := replace(translated[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if d.xmlDecl != "" {
+		if _, err := io.WriteString(w, d.xmlDecl+"\n"); err != nil {
+			return err
+		}
+	}
+	if d.nodes == nil {
+		return html.Render(w, d.root)
+	}
+	for n := d.root.FirstChild; n != nil; n = n.NextSibling {
+		if err := html.Render(w, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse 将译文中的文本转义、占位符换回标签后解析成节点
+func (seg *htmlSegment) parse(translated string) ([]*html.Node, error) {
+	var sb strings.Builder
+	sb.WriteString(html.EscapeString(seg.lead))
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringSubmatchIndex(translated, -1) {
+		sb.WriteString(escapeText(translated[last:loc[0]]))
+		last = loc[1]
+		id, err := strconv.Atoi(translated[loc[2]:loc[3]])
+		if err != nil || id >= len(seg.tags) {
+			sb.WriteString(escapeText(translated[loc[0]:loc[1]]))
+			continue
+		}
+		sb.WriteString(seg.tags[id]())
+	}
+	sb.WriteString(escapeText(translated[last:]))
+	sb.WriteString(html.EscapeString(seg.trail))
+
+	nodes, err := html.ParseFragment(strings.NewReader(sb.String()), seg.parent)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing translated html: %w", err)
+	}
+	return nodes, nil
+}
+
+// replace 用译文替换原有的行内节点
+func (seg *htmlSegment) replace(translated string) error {
+	nodes, err := seg.parse(translated)
+	if err != nil {
+		return err
+	}
+	first := seg.nodes[0]
+	for _, n := range nodes {
+		seg.parent.InsertBefore(n, first)
+	}
+	for _, n := range seg.nodes {
+		seg.parent.RemoveChild(n)
+	}
+	return nil
+}
+
+// appendTranslation 双语输出，译文放在原文之后：段落等元素只含这段内容时复制该元素放译文，
+// 否则换行后接在原文之后；title等只能含文本的元素直接替换
+func (seg *htmlSegment) appendTranslation(translated, lang string) error {
+	p := seg.parent
+	if p.DataAtom == atom.Title || p.DataAtom == atom.Option {
+		return seg.replace(translated)
+	}
+	nodes, err := seg.parse(translated)
+	if err != nil {
+		return err
+	}
+
+	last := seg.nodes[len(seg.nodes)-1]
+	if htmlBilingualTags[p.DataAtom] && p.Parent != nil && p.FirstChild == seg.nodes[0] && p.LastChild == last {
+		// 复制的元素去掉id，lang改为目标语言
+		clone := &html.Node{Type: p.Type, Data: p.Data, DataAtom: p.DataAtom, Namespace: p.Namespace}
+		hasLang := false
+		for _, a := range p.Attr {
+			switch {
+			case a.Key == "id":
+				continue
+			case (a.Key == "lang" || a.Key == "xml:lang") && lang != "":
+				a.Val, hasLang = lang, true
+			}
+			clone.Attr = append(clone.Attr, a)
+		}
+		if !hasLang && lang != "" {
+			clone.Attr = append(clone.Attr, html.Attribute{Key: "lang", Val: lang})
+		}
+		for _, n := range nodes {
+			clone.AppendChild(n)
+		}
+		p.Parent.InsertBefore(clone, p.NextSibling)
+		return nil
+	}
+
+	next := last.NextSibling
+	p.InsertBefore(&html.Node{Type: html.ElementNode, Data: "br", DataAtom: atom.Br}, next)
+	for _, n := range nodes {
+		p.InsertBefore(n, next)
+	}
+	return nil
+}
+
+// escapeText 部分服务（如google的html模式）返回转义后的文本，先还原再统一转义
+func escapeText(s string) string {
+	return html.EscapeString(html.UnescapeString(s))
+}
+
+// isInline 节点是否为文本、注释，或只含行内内容的行内元素
+func isInline(n *html.Node) bool {
+	switch n.Type {
+	case html.TextNode, html.CommentNode:
+		return true
+	case html.ElementNode:
+		if !htmlInlineTags[n.DataAtom] {
+			return false
+		}
+		if htmlSkipTags[n.DataAtom] || isNoTranslate(n) {
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !isInline(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isNoTranslate(n *html.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key == "translate" && strings.EqualFold(a.Val, "no") {
+			return true
+		}
+		if a.Key == "class" && strings.Contains(" "+a.Val+" ", " notranslate ") {
+			return true
+		}
+	}
+	return false
+}
+
+// openTag 输出元素的开始标签
+func openTag(n *html.Node) string {
+	shallow := &html.Node{Type: n.Type, Data: n.Data, DataAtom: n.DataAtom, Namespace: n.Namespace, Attr: n.Attr}
+	s := renderNode(shallow)
+	return strings.TrimSuffix(s, "</"+n.Data+">")
+}
+
+func renderNode(n *html.Node) string {
+	var buf bytes.Buffer
+	html.Render(&buf, n)
+	return buf.String()
+}
+
+// expandSelfClosing 将非空元素的自闭合标签（如<a id="p1"/>）展开成开始和结束标签
+func expandSelfClosing(data []byte) []byte {
+	return selfClosingRe.ReplaceAllFunc(data, func(m []byte) []byte {
+		sub := selfClosingRe.FindSubmatch(m)
+		if htmlVoidTags[atom.Lookup(bytes.ToLower(sub[1]))] {
+			return m
+		}
+		return []byte("<" + string(sub[1]) + string(sub[2]) + "></" + string(sub[1]) + ">")
+	})
+}
+
+// collapseSpace 将连续空白合并为一个空格，与浏览器的显示一致
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			if !space {
+				sb.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHTML_Fragment(t *testing.T) {
+	src := `<p title="Greeting">Click <a href="/x" title="Go there">here</a> to <b>start</b>.</p>
+<script>var s = "hello";</script>
+<p>Use <code>go build</code> now<br>please</p>
+<div translate="no">Brand Name</div>
+<img src="a.png" alt="A cat">
+<input placeholder="Search here">
+<ul>
+  <li>First item</li>
+</ul>`
+	doc, err := ParseHTML([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Greeting",
+		"Go there",
+		"Click {ID_0}here{ID_1} to {ID_2}start{ID_3}.",
+		"Use {ID_0} now{ID_1}please",
+		"A cat",
+		"Search here",
+		"First item",
+	}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Errorf("Texts() =\n%q\nwant\n%q", got, want)
+	}
+
+	got := render(t, doc, upper(doc.Texts()))
+	for _, s := range []string{
+		`<p title="GREETING">CLICK <a href="/x" title="GO THERE">HERE</a> TO <b>START</b>.</p>`,
+		`<script>var s = "hello";</script>`,
+		`<p>USE <code>go build</code> NOW<br/>PLEASE</p>`,
+		`<div translate="no">Brand Name</div>`,
+		`<img src="a.png" alt="A CAT"/>`,
+		`<input placeholder="SEARCH HERE"/>`,
+		"<ul>\n  <li>FIRST ITEM</li>\n</ul>",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestHTML_Document(t *testing.T) {
+	src := `<!DOCTYPE html>
+<html><head><title>My &amp; Page</title><style>p { color: red; }</style></head>
+<body><p class="notranslate">Keep</p><p>Tom &amp; Jerry</p></body></html>`
+	doc, err := ParseHTML([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"My & Page", "Tom & Jerry"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Errorf("Texts() = %q, want %q", got, want)
+	}
+
+	// 服务返回转义后的文本时不应重复转义
+	got := render(t, doc, []string{"我的 &amp; 页面", "汤姆 & 杰瑞"})
+	for _, s := range []string{
+		"<!DOCTYPE html>",
+		"<title>我的 &amp; 页面</title>",
+		"<style>p { color: red; }</style>",
+		`<p class="notranslate">Keep</p>`,
+		"<p>汤姆 &amp; 杰瑞</p>",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestHTML_LiteralPlaceholders(t *testing.T) {
+	doc, err := ParseHTML([]byte(`<p>Use {id_1} and {id_2} here</p>`), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"Use {ID_0} and {ID_1} here"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	if got := render(t, doc, upper(doc.Texts())); !strings.Contains(got, "<p>USE {id_1} AND {id_2} HERE</p>") {
+		t.Errorf("rendered %s", got)
+	}
+}
+
+func TestHTML_SkipTagAttrs(t *testing.T) {
+	src := `<div><script title="t">var a = 1;</script><p>Run <code title="c">ls</code> now <img alt="Logo"></p><pre title="p">x</pre></div>`
+	doc, err := ParseHTML([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"Logo", "Run {ID_0} now {ID_1}"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	got := render(t, doc, upper(doc.Texts()))
+	for _, s := range []string{`<script title="t">`, `<code title="c">ls</code>`, `<pre title="p">`, `alt="LOGO"`} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package format
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// 插值和引用：{{var}}（i18next、vue-i18n）、%{var}（Rails）、$t(key)、@:key、<0>、<br/>等标签、%s，以及网址
+	i18nProtectRe = regexp.MustCompile(`\{\{[^{}]*\}\}|%\{\w+\}|\$t\([^)]*\)|@(?:\.\w+)?:[\w.]+` +
+		`|</?[\w.-]+\s*/?>|%(?:\d+\$)?[sd]|\{[iI][dD]_\d+\}|https?://[^\s"'<>]+`)
+	// 语言代码，如en、zh-CN、pt_BR
+	localeRe = regexp.MustCompile(`^[a-z]{2,3}(?:[-_][A-Za-z]{2,4})?$`)
+)
+
+// i18nLeaf 资源文件中待翻译的字符串值，首尾空白原样保留
+type i18nLeaf struct {
+	lead      string
+	trail     string
+	originals []string
+}
+
+// i18nTexts 收集资源文件中的字符串，按选择器过滤
+type i18nTexts struct {
+	selectors [][]string
+	texts     []string
+	leaves    []*i18nLeaf
+}
+
+func newI18nTexts(keys []string) *i18nTexts {
+	t := &i18nTexts{}
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			t.selectors = append(t.selectors, parseSelector(k))
+		}
+	}
+	return t
+}
+
+// add 路径被选中且有可翻译的文字时，返回片段下标，否则返回-1
+func (t *i18nTexts) add(path []string, value string) int {
+	if !t.selected(path) {
+		return -1
+	}
+	trimmed := strings.TrimSpace(value)
+	text, originals := protectICU(trimmed)
+	if !hasLetter(placeholderRe.ReplaceAllString(text, "")) {
+		return -1
+	}
+	start := strings.Index(value, trimmed)
+	t.leaves = append(t.leaves, &i18nLeaf{lead: value[:start], trail: value[start+len(trimmed):], originals: originals})
+	t.texts = append(t.texts, text)
+	return len(t.texts) - 1
+}
+
+func (t *i18nTexts) restore(seg int, translated string) string {
+	leaf := t.leaves[seg]
+	return leaf.lead + restoreText(translated, leaf.originals) + leaf.trail
+}
+
+// selected 没有选择器时全选；选择器匹配路径本身或其上层路径时选中
+func (t *i18nTexts) selected(path []string) bool {
+	if len(t.selectors) == 0 {
+		return true
+	}
+	for _, sel := range t.selectors {
+		if matchSelector(sel, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSelector 解析类JSONPath的选择器，如$.home.*、errors.**、items[0].title，
+// *匹配一级，**匹配任意多级
+func parseSelector(s string) []string {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "$"), ".")
+	s = strings.NewReplacer("[", ".", "]", "").Replace(s)
+	var segs []string
+	for _, seg := range strings.Split(s, ".") {
+		if seg != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+func matchSelector(sel, path []string) bool {
+	if len(sel) == 0 { // 选择器已匹配完，path在其之下
+		return true
+	}
+	if sel[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSelector(sel[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || (sel[0] != "*" && sel[0] != path[0]) {
+		return false
+	}
+	return matchSelector(sel[1:], path[1:])
+}
+
+// protectICU 保护ICU消息格式的结构和插值：{name}、{n, number}整体换成占位符；
+// {n, plural, one {...} other {...}}只保护骨架，各分支的文字仍翻译
+func protectICU(s string) (string, []string) {
+	p := &icuProtector{}
+	p.parse(s, false)
+	return p.result()
+}
+
+// icuProtector 按ICU消息格式的结构生成带占位符的文本
+type icuProtector struct {
+	protector
+}
+
+// parse 处理消息文本，inPlural时#表示数值
+func (p *icuProtector) parse(s string, inPlural bool) {
+	for i := 0; i < len(s); {
+		if loc := i18nProtectRe.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+			p.keep(s[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+		switch {
+		case s[i] == '{':
+			end := matchBrace(s, i)
+			if end < 0 { // 不成对的花括号按普通文字处理
+				p.text(s[i:])
+				return
+			}
+			p.argument(s[i : end+1])
+			i = end + 1
+		case s[i] == '#' && inPlural:
+			p.keep("#")
+			i++
+		default:
+			// 普通文字直到下一个花括号、#或插值
+			j := len(s)
+			if loc := i18nProtectRe.FindStringIndex(s[i+1:]); loc != nil {
+				j = i + 1 + loc[0]
+			}
+			stops := "{"
+			if inPlural {
+				stops = "{#"
+			}
+			if k := strings.IndexAny(s[i+1:j], stops); k >= 0 {
+				j = i + 1 + k
+			}
+			p.text(s[i:j])
+			i = j
+		}
+	}
+}
+
+// argument 处理{...}参数，plural、select的各分支递归处理
+func (p *icuProtector) argument(arg string) {
+	parts := strings.SplitN(arg[1:len(arg)-1], ",", 3)
+	if len(parts) < 3 {
+		p.keep(arg)
+		return
+	}
+	typ := strings.TrimSpace(parts[1])
+	if typ != "plural" && typ != "select" && typ != "selectordinal" {
+		p.keep(arg)
+		return
+	}
+
+	p.keep(arg[:len(arg)-1-len(parts[2])])
+	opts := parts[2]
+	for i := 0; i < len(opts); {
+		open := strings.IndexByte(opts[i:], '{')
+		if open < 0 {
+			p.keep(opts[i:])
+			break
+		}
+		open += i
+		end := matchBrace(opts, open)
+		if end < 0 {
+			p.keep(opts[i:])
+			break
+		}
+		p.keep(opts[i : open+1]) // 选择器，如" one {"
+		p.parse(opts[open+1:end], typ != "select")
+		p.keep("}")
+		i = end + 1
+	}
+	p.keep("}")
+}
+
+// matchBrace 返回与s[open]的"{"配对的"}"的下标，没有时返回-1
+func matchBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// rootLocale 只有一个顶层键且是语言代码时（如Rails的en:），返回该键
+func rootLocale(keys []string) (string, bool) {
+	if len(keys) == 1 && localeRe.MatchString(keys[0]) {
+		return keys[0], true
+	}
+	return "", false
+}
+
+func pathAppend(path []string, seg any) []string {
+	result := make([]string, len(path), len(path)+1)
+	copy(result, path)
+	switch v := seg.(type) {
+	case string:
+		return append(result, v)
+	case int:
+		return append(result, strconv.Itoa(v))
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProtectICU(t *testing.T) {
+	tests := []struct {
+		src       string
+		text      string
+		originals []string
+	}{
+		{"Hello {{name}}!", "Hello {ID_0}!", []string{"{{name}}"}},
+		{"Hi %{user}, see $t(common.more)", "Hi {ID_0}, see {ID_1}", []string{"%{user}", "$t(common.more)"}},
+		{"{count, number} items", "{ID_0} items", []string{"{count, number}"}},
+		{
+			"You have {count, plural, =0 {no messages} one {# message} other {# messages}}.",
+			"You have {ID_0}no messages{ID_1} message{ID_2} messages{ID_3}.",
+			[]string{"{count, plural, =0 {", "} one {#", "} other {#", "}}"},
+		},
+		{"{gender, select, male {He} other {They}} left", "{ID_0}He{ID_1}They{ID_2} left",
+			[]string{"{gender, select, male {", "} other {", "}}"}},
+		{"Click <0>here</0>", "Click {ID_0}here{ID_1}", []string{"<0>", "</0>"}},
+	}
+	for _, tt := range tests {
+		text, originals := protectICU(tt.src)
+		if text != tt.text || !slices.Equal(originals, tt.originals) {
+			t.Errorf("protectICU(%q) = %q, %q, want %q, %q", tt.src, text, originals, tt.text, tt.originals)
+		}
+		if got := restoreText(text, originals); got != tt.src {
+			t.Errorf("restore of %q = %q", tt.src, got)
+		}
+	}
+}
+
+func TestMatchSelector(t *testing.T) {
+	tests := []struct {
+		sel  string
+		path []string
+		want bool
+	}{
+		{"$.home.title", []string{"home", "title"}, true},
+		{"home", []string{"home", "title"}, true},
+		{"home.*", []string{"home", "title"}, true},
+		{"*.title", []string{"about", "title"}, true},
+		{"**.title", []string{"en", "pages", "about", "title"}, true},
+		{"items[0]", []string{"items", "0", "name"}, true},
+		{"items[*].name", []string{"items", "1", "name"}, true},
+		{"home.title", []string{"home", "subtitle"}, false},
+		{"errors.**", []string{"home", "title"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchSelector(parseSelector(tt.sel), tt.path); got != tt.want {
+			t.Errorf("matchSelector(%q, %v) = %v, want %v", tt.sel, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	src := `{
+  "home": {
+    "title": "Welcome, {{name}}",
+    "count": 3,
+    "items": ["First", " Second "]
+  },
+  "empty": "",
+  "url": "https://example.com"
+}
+`
+	doc, err := ParseJSON([]byte(src), Options{ToLang: "zh-CN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Welcome, {ID_0}", "First", "Second"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	got := render(t, doc, []string{"欢迎，{ID_0}", "第一<", "第二"})
+	wantOut := strings.NewReplacer(
+		`"Welcome, {{name}}"`, `"欢迎，{{name}}"`,
+		`"First"`, `"第一<"`,
+		`" Second "`, `" 第二 "`,
+	).Replace(src)
+	if got != wantOut {
+		t.Errorf("rendered:\n%s\nwant:\n%s", got, wantOut)
+	}
+
+	doc, err = ParseJSON([]byte(src), Options{Keys: []string{"$.home.items"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Texts(); !slices.Equal(got, want[1:]) {
+		t.Errorf("Texts() with keys = %q, want %q", got, want[1:])
+	}
+}
+
+func TestJSON_RootLocale(t *testing.T) {
+	src := `{"en": {"hello": "Hello"}}`
+	doc, err := ParseJSON([]byte(src), Options{ToLang: "ja"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(t, doc, []string{"こんにちは"}); got != `{"ja": {"hello": "こんにちは"}}` {
+		t.Errorf("rendered %s", got)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	src := `en:
+  # greeting shown on the home page
+  hello: "Hello %{name}"
+  count: 3
+  list:
+    - One
+    - Two
+`
+	doc, err := ParseYAML([]byte(src), Options{ToLang: "zh-CN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Hello {ID_0}", "One", "Two"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	got := render(t, doc, []string{"你好 {ID_0}", "一", "二"})
+	wantOut := `zh-CN:
+  # greeting shown on the home page
+  hello: "你好 %{name}"
+  count: 3
+  list:
+    - 一
+    - 二
+`
+	if got != wantOut {
+		t.Errorf("rendered:\n%s\nwant:\n%s", got, wantOut)
+	}
+}
+
+func TestYAML_Keys(t *testing.T) {
+	src := "en:\n  home:\n    title: Home\n  errors:\n    required: Required\n"
+	doc, err := ParseYAML([]byte(src), Options{ToLang: "de", Keys: []string{"$.en.errors.**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"Required"}; !slices.Equal(got, want) {
+		t.Errorf("Texts() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonFrame 解析时所在的对象或数组
+type jsonFrame struct {
+	isObject bool
+	key      string // 对象中下一个值的键
+	wantKey  bool   // 对象中下一个字符串是键
+	index    int    // 数组中下一个值的下标
+}
+
+type jsonDoc struct {
+	parts []part // seg为i18n.texts的下标，langSeg为改成目标语言的顶层键
+	i18n  *i18nTexts
+	lang  string
+}
+
+// ParseJSON 解析i18next、vue-i18n等JSON资源文件，只翻译字符串值，键和格式原样保留。
+// ICU消息格式和{{var}}等插值用占位符保护；opts.Keys不为空时只翻译选中的键
+func ParseJSON(data []byte, opts Options) (Document, error) {
+	d := &jsonDoc{i18n: newI18nTexts(opts.Keys), lang: opts.ToLang}
+
+	// 顶层只有一个语言代码的键时，改为目标语言
+	var root map[string]json.RawMessage
+	renameRoot := ""
+	if json.Unmarshal(data, &root) == nil && opts.ToLang != "" {
+		keys := make([]string, 0, len(root))
+		for k := range root {
+			keys = append(keys, k)
+		}
+		if k, ok := rootLocale(keys); ok && bytes.HasPrefix(bytes.TrimSpace(root[k]), []byte("{")) {
+			renameRoot = k
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var stack []*jsonFrame
+	var path []string
+	last := 0
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing json: %w", err)
+		}
+		end := int(dec.InputOffset())
+
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				if top != nil {
+					path = append(path, top.next())
+				}
+				stack = append(stack, &jsonFrame{isObject: delim == '{', wantKey: delim == '{'})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 {
+					path = path[:len(path)-1]
+				}
+			}
+			continue
+		}
+
+		if top != nil && top.isObject && top.wantKey {
+			top.key, top.wantKey = tok.(string), false
+			if len(stack) == 1 && renameRoot != "" && top.key == renameRoot {
+				d.splice(data, &last, start, end, langSeg)
+			}
+			continue
+		}
+		var leafPath []string
+		if top != nil {
+			leafPath = pathAppend(path, top.next())
+		}
+		if s, ok := tok.(string); ok {
+			if seg := d.i18n.add(leafPath, s); seg >= 0 {
+				d.splice(data, &last, start, end, seg)
+			}
+		}
+	}
+	d.parts = append(d.parts, part{raw: string(data[last:]), seg: -1})
+	return d, nil
+}
+
+// next 返回当前值的路径，并准备读取下一个值
+func (f *jsonFrame) next() string {
+	if f.isObject {
+		f.wantKey = true
+		return f.key
+	}
+	f.index++
+	return strconv.Itoa(f.index - 1)
+}
+
+// splice 将data[start:end]中的字符串字面量替换为第seg个片段的译文，seg为langSeg时替换为目标语言
+func (d *jsonDoc) splice(data []byte, last *int, start, end int, seg int) {
+	// Token的范围包含前面的空白、逗号和冒号
+	quote := start + bytes.IndexByte(data[start:end], '"')
+	d.parts = append(d.parts, part{raw: string(data[*last:quote]), seg: -1}, part{seg: seg})
+	*last = end
+}
+
+func (d *jsonDoc) Texts() []string {
+	return d.i18n.texts
+}
+
+func (d *jsonDoc) Render(w io.Writer, translated []string) error {
+	return d.RenderLang(w, translated, d.lang)
+}
+
+func (d *jsonDoc) RenderLang(w io.Writer, translated []string, toLang string) error {
+	writer := bufio.NewWriter(w)
+	for _, p := range d.parts {
+		s := p.raw
+		switch {
+		case p.seg >= 0:
+			s = jsonString(d.i18n.restore(p.seg, translated[p.seg]))
+		case p.seg == langSeg:
+			s = jsonString(toLang)
+		}
+		if _, err := writer.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// jsonString 编码为JSON字符串，不转义<>&
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
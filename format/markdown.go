@@ -0,0 +1,310 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	mdFenceRe       = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
+	mdHeadingRe     = regexp.MustCompile(`^(\s{0,3}#{1,6}\s+)(.*?)(\s+#+)?\s*$`)
+	mdEmptyHeadRe   = regexp.MustCompile(`^\s{0,3}#{1,6}\s*$`)
+	mdHRRe          = regexp.MustCompile(`^\s{0,3}((-\s*){3,}|(\*\s*){3,}|(_\s*){3,})$`)
+	mdSetextRe      = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdListRe        = regexp.MustCompile(`^(\s*(?:[-*+]|\d{1,9}[.)])\s+(?:\[[ xX]\]\s+)?)(.*)$`)
+	mdQuoteRe       = regexp.MustCompile(`^(\s{0,3}>\s?)+`)
+	mdTableDelimRe  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdLinkDefRe     = regexp.MustCompile(`^\s{0,3}\[[^\]^][^\]]*\]:\s*\S`)
+	mdFootnoteDefRe = regexp.MustCompile(`^(\s{0,3}\[\^[^\]]+\]:\s*)(.*)$`)
+	mdHTMLRe        = regexp.MustCompile(`^\s{0,3}</?[a-zA-Z][^>]*>?`)
+	mdIndentCodeRe  = regexp.MustCompile(`^( {4}|\t)`)
+
+	// 行内不翻译的内容：代码、自动链接、HTML标签和注释、脚注引用、链接地址、引用链接的标签、网址
+	mdInlineRe = regexp.MustCompile("```[^`]+```|``.+?``|`[^`]+`" +
+		`|<https?://[^>]+>|<!--.*?-->|</?[a-zA-Z][^>]*>` +
+		`|\[\^[^\]]+\]` +
+		`|\]\([^()\s]*(?:\([^()\s]*\)[^()\s]*)*(?:\s+"[^"]*")?\)` +
+		`|\]\[[^\]]*\]` +
+		`|https?://[^\s<>()\[\]]+` +
+		`|\{[iI][dD]_\d+\}` +
+		`|(?: {2,}|\\)\r?\n[ \t>]*`) // 段落中的硬换行，连同下一行的缩进和引用前缀
+)
+
+// markdownParser 逐行解析，段落（含列表项、引用）跨行时合并成一个片段
+type markdownParser struct {
+	t   *template
+	eol string
+
+	para      []string // 正在累积的段落行，硬换行的行与下一行合并
+	paraBreak string   // 段落最后一行的硬换行标记
+	paraStart string   // 段落首行的前缀
+	paraQuote string   // 段落所在引用的前缀，引用层级变化时段落结束
+	inList    bool     // 是否在列表中，列表中的缩进行不是代码块
+}
+
+// ParseMarkdown 提取标题、段落、列表项、表格单元格、图片替代文字等，
+// 代码块、HTML块和注释、front matter、链接定义原样保留
+func ParseMarkdown(data []byte, opts Options) (Document, error) {
+	lines, eol, finalEOL := splitLines(data)
+	p := &markdownParser{
+		t:   &template{protect: mdInlineRe, singleLine: true},
+		eol: eol,
+	}
+	p.parse(lines)
+	if !finalEOL {
+		trimFinalEOL(p.t.parts, eol)
+	}
+	return p.t, nil
+}
+
+func (p *markdownParser) parse(lines []string) {
+	i := skipFrontMatter(lines)
+	for _, line := range lines[:i] {
+		p.raw(line)
+	}
+
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		quote := mdQuoteRe.FindString(line)
+		content := line[len(quote):]
+
+		// 跨行的原样块：代码块、公式块、HTML注释
+		if end := rawBlockEnd(lines, i, quote); end >= 0 {
+			p.flush()
+			for ; i < end; i++ {
+				p.raw(lines[i])
+			}
+			p.raw(lines[end])
+			continue
+		}
+
+		if strings.TrimSpace(content) == "" {
+			p.flush()
+			p.raw(line)
+			continue
+		}
+
+		// 段落的延续行
+		if p.para != nil && quote == p.paraQuote && !isBlockStart(content) {
+			if mdSetextRe.MatchString(content) { // setext标题的下划线
+				p.flush()
+				p.raw(line)
+				continue
+			}
+			text, mark := hardBreak(content)
+			if p.paraBreak != "" { // 硬换行保留在片段中，翻译时用占位符保护
+				indent := line[:len(line)-len(strings.TrimLeft(content, " \t"))]
+				p.para[len(p.para)-1] += p.paraBreak + p.eol + indent + text
+			} else {
+				p.para = append(p.para, text)
+			}
+			p.paraBreak = mark
+			continue
+		}
+		p.flush()
+
+		// 表格：表头行后紧跟分隔行
+		if strings.Contains(content, "|") && i+1 < len(lines) {
+			next := lines[i+1][len(mdQuoteRe.FindString(lines[i+1])):]
+			if strings.Contains(next, "|") && mdTableDelimRe.MatchString(next) {
+				p.tableRow(quote, content)
+				p.raw(lines[i+1])
+				for i += 2; i < len(lines); i++ {
+					q := mdQuoteRe.FindString(lines[i])
+					row := lines[i][len(q):]
+					if strings.TrimSpace(row) == "" || !strings.Contains(row, "|") {
+						break
+					}
+					p.tableRow(q, row)
+				}
+				i--
+				continue
+			}
+		}
+
+		switch {
+		case mdHRRe.MatchString(content), mdEmptyHeadRe.MatchString(content), mdLinkDefRe.MatchString(content):
+			p.raw(line)
+		case mdHTMLRe.MatchString(content):
+			// HTML块持续到空行
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				p.raw(lines[i])
+			}
+			i--
+		case mdHeadingRe.MatchString(content):
+			m := mdHeadingRe.FindStringSubmatch(content)
+			p.t.addRaw(quote + m[1])
+			p.t.addText(m[2])
+			p.t.addRaw(m[3] + p.eol)
+		case mdIndentCodeRe.MatchString(content) && !p.inList:
+			p.raw(line)
+		case mdFootnoteDefRe.MatchString(content):
+			m := mdFootnoteDefRe.FindStringSubmatch(content)
+			p.start(quote, quote+m[1], m[2])
+		case mdListRe.MatchString(content):
+			m := mdListRe.FindStringSubmatch(content)
+			p.inList = true
+			p.start(quote, quote+m[1], m[2])
+		default:
+			if !unicode.IsSpace(rune(content[0])) {
+				p.inList = false
+			}
+			indent := content[:len(content)-len(strings.TrimLeft(content, " \t"))]
+			p.start(quote, quote+indent, content[len(indent):])
+		}
+	}
+	p.flush()
+}
+
+func (p *markdownParser) raw(line string) {
+	p.t.addRaw(line + p.eol)
+}
+
+func (p *markdownParser) start(quote, prefix, content string) {
+	p.paraQuote = quote
+	p.paraStart = prefix
+	text, mark := hardBreak(content)
+	p.para = []string{text}
+	p.paraBreak = mark
+}
+
+// flush 结束正在累积的段落，合并为一行输出
+func (p *markdownParser) flush() {
+	if p.para == nil {
+		return
+	}
+	if p.paraBreak == `\` { // 段落末尾的反斜杠不是硬换行
+		p.para[len(p.para)-1] += `\`
+	}
+	p.t.addRaw(p.paraStart)
+	p.t.addText(joinLines(p.para))
+	p.t.addRaw(p.eol)
+	p.para = nil
+}
+
+// tableRow 表格每个单元格单独翻译，分隔符和单元格内的空白原样保留
+func (p *markdownParser) tableRow(quote, row string) {
+	p.t.addRaw(quote)
+	for i, cell := range splitTableRow(row) {
+		if i%2 == 1 { // 分隔符
+			p.t.addRaw(cell)
+		} else {
+			p.t.addText(cell)
+		}
+	}
+	p.t.addRaw(p.eol)
+}
+
+// splitTableRow 按未转义、不在行内代码中的"|"拆分，结果中单元格和分隔符交替出现
+func splitTableRow(row string) []string {
+	var result []string
+	start, inCode := 0, false
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '`':
+			inCode = !inCode
+		case '|':
+			if !inCode {
+				result = append(result, row[start:i], "|")
+				start = i + 1
+			}
+		}
+	}
+	return append(result, row[start:])
+}
+
+// skipFrontMatter 返回开头的YAML(---)或TOML(+++) front matter的行数
+func skipFrontMatter(lines []string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	delim := strings.TrimSpace(lines[0])
+	if delim != "---" && delim != "+++" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if s := strings.TrimSpace(lines[i]); s == delim || (delim == "---" && s == "...") {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// rawBlockEnd 第i行开始代码块、公式块或HTML注释时，返回其结束行的下标，否则返回-1。
+// 未闭合的块持续到文末
+func rawBlockEnd(lines []string, i int, quote string) int {
+	content := lines[i][len(quote):]
+	trimmed := strings.TrimSpace(content)
+
+	var isEnd func(string) bool
+	switch {
+	case mdFenceRe.MatchString(content):
+		fence := mdFenceRe.FindStringSubmatch(content)[1]
+		isEnd = func(s string) bool {
+			s = strings.TrimSpace(s)
+			return strings.HasPrefix(s, fence) && strings.Trim(s, fence[:1]) == ""
+		}
+	case trimmed == "$$":
+		isEnd = func(s string) bool { return strings.TrimSpace(s) == "$$" }
+	case strings.HasPrefix(trimmed, "<!--"):
+		if strings.Contains(trimmed[4:], "-->") {
+			return i
+		}
+		isEnd = func(s string) bool { return strings.Contains(s, "-->") }
+	default:
+		return -1
+	}
+
+	for j := i + 1; j < len(lines); j++ {
+		if isEnd(lines[j][len(mdQuoteRe.FindString(lines[j])):]) {
+			return j
+		}
+	}
+	return len(lines) - 1
+}
+
+// hardBreak 拆出行尾的硬换行标记：两个以上空格或未转义的反斜杠
+func hardBreak(content string) (text, mark string) {
+	text = strings.TrimSpace(content)
+	if trailing := strings.TrimRight(content, " "); len(content)-len(trailing) >= 2 {
+		return text, content[len(trailing):]
+	}
+	if n := len(text) - len(strings.TrimRight(text, `\`)); n%2 == 1 {
+		return text[:len(text)-1], `\`
+	}
+	return text, ""
+}
+
+// isBlockStart 该行是否开始新的块，而不是上一段落的延续
+func isBlockStart(content string) bool {
+	return mdHeadingRe.MatchString(content) || mdEmptyHeadRe.MatchString(content) ||
+		mdFenceRe.MatchString(content) || mdListRe.MatchString(content) ||
+		mdHTMLRe.MatchString(content) || mdFootnoteDefRe.MatchString(content) ||
+		mdLinkDefRe.MatchString(content) ||
+		(mdHRRe.MatchString(content) && !mdSetextRe.MatchString(content))
+}
+
+// joinLines 合并段落的各行，中日韩文字之间不加空格
+func joinLines(lines []string) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(sb.String())
+			next, _ := utf8.DecodeRuneInString(line)
+			if !isCJK(prev) || !isCJK(next) {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		unicode.In(r, unicode.Punct) && r > unicode.MaxLatin1
+}
@@ -0,0 +1,149 @@
+package format
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// upper 模拟翻译：转为大写，占位符转为小写以检查大小写容错
+func upper(texts []string) []string {
+	result := make([]string, len(texts))
+	for i, text := range texts {
+		result[i] = placeholderRe.ReplaceAllStringFunc(strings.ToUpper(text), strings.ToLower)
+	}
+	return result
+}
+
+func render(t *testing.T, doc Document, translated []string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := doc.Render(&buf, translated); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestMarkdown_Segments(t *testing.T) {
+	src := `---
+title: Hello
+---
+
+# Getting started #
+
+Install with ` + "`go install`" + ` and read
+the [docs](https://example.com/docs "Docs").
+
+<!-- not translated
+  at all -->
+
+` + "```go" + `
+fmt.Println("hello")
+` + "```" + `
+
+- [ ] first item
+- second item
+  continued here[^1]
+
+> quoted text
+
+| Name | Description |
+| ---- | ----------- |
+| ` + "`id`" + ` | the identifier |
+
+![logo image](logo.png)
+
+[docs]: https://example.com
+[^1]: A footnote.
+`
+	doc, err := ParseMarkdown([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Getting started",
+		"Install with {ID_0} and read the [docs]{ID_1}.",
+		"first item",
+		"second item continued here{ID_0}",
+		"quoted text",
+		"Name",
+		"Description",
+		"the identifier",
+		"![logo image]{ID_0}",
+		"A footnote.",
+	}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Errorf("Texts() =\n%q\nwant\n%q", got, want)
+	}
+
+	got := render(t, doc, upper(doc.Texts()))
+	for _, s := range []string{
+		"title: Hello\n",
+		"# GETTING STARTED #\n",
+		"INSTALL WITH `go install` AND READ THE [DOCS](https://example.com/docs \"Docs\").\n",
+		"<!-- not translated\n  at all -->\n",
+		"fmt.Println(\"hello\")\n",
+		"- [ ] FIRST ITEM\n",
+		"- SECOND ITEM CONTINUED HERE[^1]\n",
+		"> QUOTED TEXT\n",
+		"| NAME | DESCRIPTION |\n| ---- | ----------- |\n| `id` | THE IDENTIFIER |\n",
+		"![LOGO IMAGE](logo.png)\n",
+		"[docs]: https://example.com\n",
+		"[^1]: A FOOTNOTE.\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestMarkdown_RoundTrip(t *testing.T) {
+	srcs := []string{
+		"",
+		"no trailing newline",
+		"Title\n=====\n\ntext\r\nwith crlf\r\n",
+		"    indented code\n\n1. one\n2. two\n\n---\n",
+		"中文第一行\n中文第二行\n",
+	}
+	for _, src := range srcs {
+		doc, err := ParseMarkdown([]byte(src), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := src
+		if len(doc.Texts()) > 0 && strings.Contains(src, "中文") {
+			want = "中文第一行中文第二行\n"
+		}
+		if strings.Contains(src, "with crlf") {
+			want = "Title\r\n=====\r\n\r\ntext with crlf\r\n"
+		}
+		if got := render(t, doc, doc.Texts()); got != want {
+			t.Errorf("round trip of %q = %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestMarkdown_HardBreaks(t *testing.T) {
+	src := "Line one  \nline two\\\nline three\n\n> quoted  \n> next\n\n- item  \n  continued\n\nends with \\\n"
+	doc, err := ParseMarkdown([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Line one{ID_0}line two{ID_1}line three",
+		"quoted{ID_0}next",
+		"item{ID_0}continued",
+		`ends with \`,
+	}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, upper(doc.Texts()))
+	wantOut := "LINE ONE  \nLINE TWO\\\nLINE THREE\n\n> QUOTED  \n> NEXT\n\n- ITEM  \n  CONTINUED\n\nENDS WITH \\\n"
+	if got != wantOut {
+		t.Errorf("rendered %q, want %q", got, wantOut)
+	}
+}
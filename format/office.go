@@ -0,0 +1,311 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// officeSpec Office Open XML格式中要翻译的部件和元素（按不带命名空间前缀的元素名匹配）
+type officeSpec struct {
+	name      string
+	parts     *regexp.Regexp // 要翻译的XML部件
+	paragraph string         // 段落，其中的文字合并成一个片段
+	run       string         // 格式相同的一段文字
+	runProps  string         // run的格式
+	text      string         // 文字
+	ignore    []string       // 其中的文字不翻译，如PowerPoint的字段、Excel的注音
+	neutral   []string       // 不影响格式的元素，如Word的拼写检查标记
+}
+
+var (
+	docxSpec = &officeSpec{
+		name:      "docx",
+		parts:     regexp.MustCompile(`^word/(document|header\d*|footer\d*|footnotes|endnotes|comments)\.xml$`),
+		paragraph: "p", run: "r", runProps: "rPr", text: "t",
+		neutral: []string{"proofErr", "lastRenderedPageBreak", "softHyphen"},
+	}
+	pptxSpec = &officeSpec{
+		name:      "pptx",
+		parts:     regexp.MustCompile(`^ppt/(slides/slide\d+|notesSlides/notesSlide\d+)\.xml$`),
+		paragraph: "p", run: "r", runProps: "rPr", text: "t",
+		ignore: []string{"fld"},
+	}
+	xlsxSpec = &officeSpec{
+		name:      "xlsx",
+		parts:     regexp.MustCompile(`^xl/sharedStrings\.xml$`),
+		paragraph: "si", run: "r", runProps: "rPr", text: "t",
+		ignore: []string{"rPh"},
+	}
+
+	// 部件路径中的序号，如ppt/slides/slide10.xml
+	officeNumRe = regexp.MustCompile(`^(.*?)(\d*)\.xml$`)
+)
+
+// ParseDOCX 解析Word文档，翻译正文、页眉页脚、脚注尾注和批注
+func ParseDOCX(data []byte, opts Options) (Document, error) {
+	return parseOffice(data, docxSpec)
+}
+
+// ParsePPTX 解析PowerPoint演示文稿，翻译幻灯片和备注
+func ParsePPTX(data []byte, opts Options) (Document, error) {
+	return parseOffice(data, pptxSpec)
+}
+
+// ParseXLSX 解析Excel工作簿，翻译共享字符串表中的文字
+func ParseXLSX(data []byte, opts Options) (Document, error) {
+	return parseOffice(data, xlsxSpec)
+}
+
+// officeUnit 一个段落的译文：占位符换回格式边界的XML，首尾空白原样保留
+type officeUnit struct {
+	originals []string
+	lead      string
+	trail     string
+}
+
+// officePart 要翻译的XML部件
+type officePart struct {
+	parts  []part // seg为units的下标
+	units  []*officeUnit
+	offset int // 第一个片段在officeDoc.texts中的下标
+}
+
+type officeDoc struct {
+	zip   *zip.Reader
+	parts map[string]*officePart
+	texts []string
+}
+
+// parseOffice 解压Office文档，逐个解析要翻译的XML部件
+func parseOffice(data []byte, spec *officeSpec) (Document, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", spec.name, err)
+	}
+	d := &officeDoc{zip: r, parts: map[string]*officePart{}}
+
+	// 按slide2、slide10的顺序翻译，让相邻的内容在同一批
+	var files []*zip.File
+	for _, f := range r.File {
+		if spec.parts.MatchString(f.Name) {
+			files = append(files, f)
+		}
+	}
+	slices.SortStableFunc(files, func(a, b *zip.File) int {
+		ma, mb := officeNumRe.FindStringSubmatch(a.Name), officeNumRe.FindStringSubmatch(b.Name)
+		if c := strings.Compare(ma[1], mb[1]); c != 0 {
+			return c
+		}
+		na, _ := strconv.Atoi(ma[2])
+		nb, _ := strconv.Atoi(mb[2])
+		return na - nb
+	})
+
+	for _, f := range files {
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", f.Name, err)
+		}
+		p, texts, err := parseOfficeXML(content, spec)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", f.Name, err)
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		p.offset = len(d.texts)
+		d.parts[f.Name] = p
+		d.texts = append(d.texts, texts...)
+	}
+	return d, nil
+}
+
+// officeText 段落中的一个文字元素
+type officeText struct {
+	start   int // 文字内容的范围
+	end     int
+	text    string
+	rPr     string // 所在run的格式
+	neutral bool   // 与上一个文字元素之间只有格式相同的run边界，可以合并
+}
+
+// officeParser 解析XML部件的状态
+type officeParser struct {
+	data   []byte
+	spec   *officeSpec
+	result *officePart
+	texts  []string
+	last   int // data[last:]尚未写入parts
+
+	groups  [][]officeText // 各层段落中尚未生成片段的文字元素，文本框等会嵌套段落
+	gapOK   bool           // 上一个文字元素之后只有run边界、格式和neutral元素
+	rPr     string         // 当前run的格式
+	rPrFrom int            // 当前格式元素的起点
+	inRPr   int
+	ignore  int // 在ignore元素中的嵌套深度
+	inText  bool
+	textAt  int
+	textSB  strings.Builder
+}
+
+// parseOfficeXML 将段落中的文字合并成片段，格式不同的run之间的XML换成占位符，
+// 格式相同的run合并到第一个run中
+func parseOfficeXML(data []byte, spec *officeSpec) (*officePart, []string, error) {
+	p := &officeParser{data: data, spec: spec, result: &officePart{}}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		end := int(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p.startElement(t.Name.Local, start, end)
+		case xml.EndElement:
+			p.endElement(t.Name.Local, start, end)
+		case xml.CharData:
+			if p.inText {
+				p.textSB.Write(t)
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				p.gapOK = false
+			}
+		default:
+			p.gapOK = false
+		}
+	}
+	p.result.parts = appendRaw(p.result.parts, string(data[p.last:]))
+	return p.result, p.texts, nil
+}
+
+func (p *officeParser) startElement(name string, start, end int) {
+	switch {
+	case p.ignore > 0:
+		p.ignore++
+	case slices.Contains(p.spec.ignore, name):
+		p.ignore, p.gapOK = 1, false
+	case name == p.spec.paragraph:
+		if n := len(p.groups); n > 0 { // 嵌套的段落，先结束外层已有的文字
+			p.flush(n - 1)
+		}
+		p.groups = append(p.groups, nil)
+		p.rPr = ""
+	case p.inRPr > 0:
+		p.inRPr++
+	case name == p.spec.runProps:
+		p.inRPr, p.rPrFrom = 1, start
+	case name == p.spec.run:
+		p.rPr = ""
+	case name == p.spec.text && len(p.groups) > 0:
+		p.inText, p.textAt = true, end
+		p.textSB.Reset()
+	case !slices.Contains(p.spec.neutral, name):
+		p.gapOK = false
+	}
+}
+
+func (p *officeParser) endElement(name string, start, end int) {
+	switch {
+	case p.ignore > 0:
+		p.ignore--
+	case name == p.spec.paragraph && len(p.groups) > 0:
+		n := len(p.groups)
+		p.flush(n - 1)
+		p.groups = p.groups[:n-1]
+	case p.inRPr > 0:
+		if p.inRPr--; p.inRPr == 0 {
+			p.rPr = string(p.data[p.rPrFrom:end])
+		}
+	case p.inText && name == p.spec.text:
+		p.inText = false
+		n := len(p.groups)
+		group := p.groups[n-1]
+		t := officeText{start: p.textAt, end: start, text: p.textSB.String(), rPr: p.rPr}
+		t.neutral = len(group) > 0 && p.gapOK && group[len(group)-1].rPr == t.rPr
+		p.groups[n-1] = append(group, t)
+		p.gapOK = true
+	case name != p.spec.run && !slices.Contains(p.spec.neutral, name):
+		p.gapOK = false
+	}
+}
+
+// flush 为第i层段落中的文字元素生成片段
+func (p *officeParser) flush(i int) {
+	group := p.groups[i]
+	p.groups[i] = nil
+	p.gapOK = false
+	if len(group) == 0 {
+		return
+	}
+
+	pr := &protector{}
+	for j, t := range group {
+		if j > 0 && !t.neutral {
+			pr.keep(string(p.data[group[j-1].end:t.start]))
+		}
+		// 原文中形如占位符的文本也保护起来
+		last := 0
+		for _, loc := range placeholderRe.FindAllStringIndex(t.text, -1) {
+			pr.text(t.text[last:loc[0]])
+			pr.keep(xmlEscaper.Replace(t.text[loc[0]:loc[1]]))
+			last = loc[1]
+		}
+		pr.text(t.text[last:])
+	}
+	text, originals := pr.result()
+	if !hasLetter(placeholderRe.ReplaceAllString(text, "")) {
+		return
+	}
+
+	trimmed := strings.TrimSpace(text)
+	lead := text[:strings.Index(text, trimmed)]
+	u := &officeUnit{originals: originals, lead: lead, trail: text[len(lead)+len(trimmed):]}
+	r := p.result
+	r.parts = appendRaw(r.parts, string(p.data[p.last:group[0].start]))
+	r.parts = append(r.parts, part{seg: len(r.units)})
+	r.units = append(r.units, u)
+	p.texts = append(p.texts, trimmed)
+	p.last = group[len(group)-1].end
+}
+
+func (d *officeDoc) Texts() []string {
+	return d.texts
+}
+
+// RenderLang 不改文档的语言设置，与Render相同
+func (d *officeDoc) RenderLang(w io.Writer, translated []string, toLang string) error {
+	return d.Render(w, translated)
+}
+
+// Render 重新打包：翻译过的部件重新压缩，其余文件原样复制
+func (d *officeDoc) Render(w io.Writer, translated []string) error {
+	replaced := map[string][]byte{}
+	for name, p := range d.parts {
+		var sb strings.Builder
+		for _, pt := range p.parts {
+			if pt.seg < 0 {
+				sb.WriteString(pt.raw)
+				continue
+			}
+			u := p.units[pt.seg]
+			sb.WriteString(xmlEscaper.Replace(u.lead))
+			sb.WriteString(restoreEscaped(translated[p.offset+pt.seg], u.originals, xmlEscaper.Replace))
+			sb.WriteString(xmlEscaper.Replace(u.trail))
+		}
+		replaced[name] = []byte(sb.String())
+	}
+	return rewriteZip(w, d.zip, replaced)
+}
@@ -0,0 +1,143 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// makeZip 按顺序打包文件
+func makeZip(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, files[i+1])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// unzip 返回各文件名和内容
+func unzip(t *testing.T, data string) ([]string, map[string]string) {
+	t.Helper()
+	r, err := zip.NewReader(strings.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	contents := map[string]string{}
+	for _, f := range r.File {
+		b, err := readZipFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		contents[f.Name] = string(b)
+	}
+	return names, contents
+}
+
+func TestDOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:pPr><w:rPr><w:b/></w:rPr></w:pPr><w:r><w:t xml:space="preserve">Hello </w:t></w:r>` +
+		`<w:r><w:rPr><w:b/></w:rPr><w:t>bold</w:t></w:r><w:proofErr w:type="spellStart"/>` +
+		`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve"> world</w:t></w:r><w:r><w:t>!</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>2024</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Tom &amp; Jerry</w:t></w:r><w:r><w:tab/><w:t>Page</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+	src := makeZip(t,
+		"[Content_Types].xml", `<Types/>`,
+		"word/footer1.xml", `<w:ftr xmlns:w="w"><w:p><w:r><w:t>Confidential</w:t></w:r></w:p></w:ftr>`,
+		"word/document.xml", document,
+		"word/styles.xml", `<w:styles xmlns:w="w"><w:style><w:name w:val="Normal"/></w:style></w:styles>`,
+	)
+
+	doc, err := ParseDOCX(src, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Hello {ID_0}bold world{ID_1}!",
+		"Tom & Jerry{ID_0}Page",
+		"Confidential",
+	}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{"Hallo {ID_0}fette Welt{ID_1}!", "Tom & Jerry{ID_0}Seite", "Vertraulich"})
+	names, files := unzip(t, got)
+	if want := []string{"[Content_Types].xml", "word/footer1.xml", "word/document.xml", "word/styles.xml"}; !slices.Equal(names, want) {
+		t.Errorf("files = %q, want %q", names, want)
+	}
+	for _, s := range []string{
+		`<w:r><w:t xml:space="preserve">Hallo </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>fette Welt</w:t></w:r><w:r><w:t>!</w:t></w:r></w:p>`,
+		`<w:p><w:r><w:t>2024</w:t></w:r></w:p>`,
+		`<w:t>Tom &amp; Jerry</w:t></w:r><w:r><w:tab/><w:t>Seite</w:t>`,
+	} {
+		if !strings.Contains(files["word/document.xml"], s) {
+			t.Errorf("document.xml missing %q:\n%s", s, files["word/document.xml"])
+		}
+	}
+	if !strings.Contains(files["word/footer1.xml"], "<w:t>Vertraulich</w:t>") {
+		t.Errorf("footer1.xml = %s", files["word/footer1.xml"])
+	}
+	if files["word/styles.xml"] != `<w:styles xmlns:w="w"><w:style><w:name w:val="Normal"/></w:style></w:styles>` {
+		t.Errorf("styles.xml changed: %s", files["word/styles.xml"])
+	}
+}
+
+func TestPPTX(t *testing.T) {
+	slide := func(text string) string {
+		return `<p:sld xmlns:a="a" xmlns:p="p"><p:txBody><a:p><a:r><a:rPr lang="en-US"/><a:t>` + text +
+			`</a:t></a:r><a:fld type="slidenum"><a:t>‹#›</a:t></a:fld></a:p></p:txBody></p:sld>`
+	}
+	src := makeZip(t,
+		"ppt/slides/slide10.xml", slide("Thanks"),
+		"ppt/slides/slide2.xml", slide("Agenda"),
+		"ppt/slides/slide1.xml", slide("Welcome"),
+	)
+	doc, err := ParsePPTX(src, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"Welcome", "Agenda", "Thanks"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	_, files := unzip(t, render(t, doc, upper(doc.Texts())))
+	if got := files["ppt/slides/slide10.xml"]; got != slide("THANKS") {
+		t.Errorf("slide10.xml = %s", got)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	shared := `<sst xmlns="main" count="3"><si><t>Name</t></si><si><t>42</t></si>` +
+		`<si><r><t xml:space="preserve">Total </t></r><r><rPr><b/></rPr><t>amount</t></r>` +
+		`<rPh sb="0" eb="1"><t>ソウ</t></rPh></si></sst>`
+	src := makeZip(t, "xl/sharedStrings.xml", shared, "xl/worksheets/sheet1.xml", `<worksheet/>`)
+	doc, err := ParseXLSX(src, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"Name", "Total {ID_0}amount"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	_, files := unzip(t, render(t, doc, []string{"名称", "合计{ID_0}金额"}))
+	want := `<sst xmlns="main" count="3"><si><t>名称</t></si><si><t>42</t></si>` +
+		`<si><r><t xml:space="preserve">合计</t></r><r><rPr><b/></rPr><t>金额</t></r>` +
+		`<rPh sb="0" eb="1"><t>ソウ</t></rPh></si></sst>`
+	if got := files["xl/sharedStrings.xml"]; got != want {
+		t.Errorf("sharedStrings.xml =\n%s\nwant\n%s", got, want)
+	}
+}
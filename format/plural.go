@@ -0,0 +1,48 @@
+package format
+
+import "strings"
+
+// 各语言CLDR的复数类别，未列出的语言按one、other
+var pluralCategories = map[string][]string{
+	"ja": {"other"}, "ko": {"other"}, "zh": {"other"}, "vi": {"other"}, "th": {"other"},
+	"id": {"other"}, "ms": {"other"}, "lo": {"other"}, "my": {"other"}, "km": {"other"},
+	"ru": {"one", "few", "many", "other"}, "uk": {"one", "few", "many", "other"},
+	"be": {"one", "few", "many", "other"}, "pl": {"one", "few", "many", "other"},
+	"lt": {"one", "few", "many", "other"}, "cs": {"one", "few", "many", "other"},
+	"sk": {"one", "few", "many", "other"},
+	"hr": {"one", "few", "other"}, "bs": {"one", "few", "other"}, "sr": {"one", "few", "other"},
+	"ro": {"one", "few", "other"},
+	"lv": {"zero", "one", "other"},
+	"he": {"one", "two", "other"},
+	"sl": {"one", "two", "few", "other"},
+	"ga": {"one", "two", "few", "many", "other"},
+	"ar": {"zero", "one", "two", "few", "many", "other"},
+	"cy": {"zero", "one", "two", "few", "many", "other"},
+}
+
+// pluralCategoriesFor 返回目标语言的复数类别，lang为空时返回nil
+func pluralCategoriesFor(lang string) []string {
+	if lang == "" {
+		return nil
+	}
+	lang = strings.ReplaceAll(lang, "_", "-")
+	if c, ok := pluralCategories[lang]; ok {
+		return c
+	}
+	if c, ok := pluralCategories[strings.ToLower(strings.SplitN(lang, "-", 2)[0])]; ok {
+		return c
+	}
+	return []string{"one", "other"}
+}
+
+// pluralSource 为目标语言的复数类别选择原文：同名类别，没有时用other，再没有时用最后一个
+func pluralSource(category string, sources []string) int {
+	for _, want := range []string{category, "other"} {
+		for i, s := range sources {
+			if s == want {
+				return i
+			}
+		}
+	}
+	return len(sources) - 1
+}
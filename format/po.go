@@ -0,0 +1,381 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// printf风格的格式符（%d、%1$s、%(name)s、%[1]d）、python的{name}，以及换行
+	poProtectRe = regexp.MustCompile(`%(?:\d+\$|\([A-Za-z_]\w*\)|\[\d+\])?[-+#0]*(?:\d+|\*)?(?:\.(?:\d+|\*))?` +
+		`(?:hh|h|ll|l|L|q|j|z|t)?[diouxXeEfFgGaAcspqvTtwbn%]` +
+		`|\{[\w.\[\]]*(?:![rsa])?(?::[^{}]*)?\}|\n`)
+	poKeywordRe  = regexp.MustCompile(`^(msgctxt|msgid_plural|msgid|msgstr(?:\[\d+\])?)\s+(".*")\s*$`)
+	poNpluralsRe = regexp.MustCompile(`nplurals\s*=\s*(\d+)`)
+)
+
+// 各语言的复数形式，未列出的语言按nplurals=2; plural=(n != 1)
+var poPluralForms = map[string]string{
+	"ja": "nplurals=1; plural=0;", "ko": "nplurals=1; plural=0;", "zh": "nplurals=1; plural=0;",
+	"vi": "nplurals=1; plural=0;", "th": "nplurals=1; plural=0;", "id": "nplurals=1; plural=0;",
+	"ms": "nplurals=1; plural=0;", "lo": "nplurals=1; plural=0;", "my": "nplurals=1; plural=0;",
+	"fr": "nplurals=2; plural=(n > 1);", "pt-BR": "nplurals=2; plural=(n > 1);", "tr": "nplurals=2; plural=(n > 1);",
+	"ru": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"uk": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"be": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"sr": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"hr": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"bs": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"pl": "nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"cs": "nplurals=3; plural=(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2;",
+	"sk": "nplurals=3; plural=(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2;",
+	"lt": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && (n%100<10 || n%100>=20) ? 1 : 2);",
+	"lv": "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2);",
+	"ro": "nplurals=3; plural=(n==1 ? 0 : (n==0 || (n%100 > 0 && n%100 < 20)) ? 1 : 2);",
+	"sl": "nplurals=4; plural=(n%100==1 ? 0 : n%100==2 ? 1 : n%100==3 || n%100==4 ? 2 : 3);",
+	"ga": "nplurals=5; plural=n==1 ? 0 : n==2 ? 1 : (n>2 && n<7) ? 2 :(n>6 && n<11) ? 3 : 4;",
+	"ar": "nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);",
+}
+
+// pluralForms 返回目标语言的Plural-Forms
+func pluralForms(lang string) string {
+	lang = strings.ReplaceAll(lang, "_", "-")
+	if pf, ok := poPluralForms[lang]; ok {
+		return pf
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	if pf, ok := poPluralForms[strings.ToLower(base)]; ok {
+		return pf
+	}
+	return "nplurals=2; plural=(n != 1);"
+}
+
+// poEntry PO文件中以空行分隔的一个条目
+type poEntry struct {
+	lines       []string
+	flags       int // "#,"行的下标，没有时为-1
+	msgstrStart int // msgstr各行的范围，没有时为-1
+	msgstrEnd   int
+	msgid       string
+	msgidPlural string
+	msgstr      []string
+	fuzzy       bool
+
+	segs []int // 待翻译的片段：msgid，有复数时再加msgid_plural
+}
+
+type poDoc struct {
+	entries      []*poEntry
+	eol          string
+	finalEOL     bool
+	nplurals     int
+	singular     bool // msgstr[0]只用于单数，可以用单数原文的译文
+	texts        []string
+	placeholders [][]string
+}
+
+// ParsePO 解析gettext的PO/POT文件，只翻译msgstr为空或标记为fuzzy的条目，
+// 复数条目按目标语言的复数形式数量生成msgstr[n]，机器翻译的条目标记为fuzzy
+func ParsePO(data []byte, opts Options) (Document, error) {
+	lines, eol, finalEOL := splitLines(data)
+	d := &poDoc{eol: eol, finalEOL: finalEOL}
+
+	var block []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			d.addEntry(block)
+			block = nil
+			d.entries = append(d.entries, &poEntry{lines: []string{line}, flags: -1, msgstrStart: -1})
+			continue
+		}
+		block = append(block, line)
+	}
+	d.addEntry(block)
+
+	plural := slices.ContainsFunc(d.entries, func(e *poEntry) bool {
+		return len(e.segs) > 1 // 有待翻译的复数条目时才需要确定复数形式
+	})
+	pf := d.updateHeader(opts.ToLang, plural)
+	d.nplurals, d.singular = poNplurals(pf), poSingularForm0(pf)
+	return d, nil
+}
+
+func (d *poDoc) addEntry(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	e := &poEntry{lines: lines, flags: -1, msgstrStart: -1}
+	d.entries = append(d.entries, e)
+
+	var key string
+	hasMsgid := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#~") { // 已废弃的条目
+			return
+		}
+		if strings.HasPrefix(line, "#,") {
+			e.flags = i
+			e.fuzzy = strings.Contains(line, "fuzzy")
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		value := ""
+		if m := poKeywordRe.FindStringSubmatch(line); m != nil {
+			key, value = m[1], m[2]
+			if strings.HasPrefix(key, "msgstr") {
+				if e.msgstrStart < 0 {
+					e.msgstrStart = i
+				}
+				e.msgstr = append(e.msgstr, "")
+			}
+			if key == "msgid" {
+				hasMsgid = true
+			}
+		} else if strings.HasPrefix(strings.TrimSpace(line), `"`) {
+			value = strings.TrimSpace(line)
+		} else {
+			continue
+		}
+		if strings.HasPrefix(key, "msgstr") {
+			e.msgstrEnd = i + 1
+		}
+
+		s := poUnquote(value)
+		switch {
+		case key == "msgid":
+			e.msgid += s
+		case key == "msgid_plural":
+			e.msgidPlural += s
+		case strings.HasPrefix(key, "msgstr"):
+			e.msgstr[len(e.msgstr)-1] += s
+		}
+	}
+
+	// 头部条目（msgid为空）不翻译，已翻译且不是fuzzy的条目不翻译
+	if !hasMsgid || e.msgid == "" || e.msgstrStart < 0 {
+		return
+	}
+	translated := false
+	for _, s := range e.msgstr {
+		translated = translated || s != ""
+	}
+	if translated && !e.fuzzy {
+		return
+	}
+
+	e.segs = append(e.segs, d.addText(e.msgid))
+	if e.msgidPlural != "" {
+		e.segs = append(e.segs, d.addText(e.msgidPlural))
+	}
+}
+
+func (d *poDoc) addText(s string) int {
+	text, originals := protectText(s, poProtectRe)
+	d.texts = append(d.texts, text)
+	d.placeholders = append(d.placeholders, originals)
+	return len(d.texts) - 1
+}
+
+// updateHeader 把头部的Language设为目标语言，并返回复数形式的数量：
+// 头部已为同一语言声明有效的Plural-Forms时沿用，否则替换为目标语言的Plural-Forms，
+// 包括POT模板中的"nplurals=INTEGER; plural=EXPRESSION;"。没有Plural-Forms时只在有复数条目时补上
+func (d *poDoc) updateHeader(toLang string, plural bool) string {
+	pf := pluralForms(toLang)
+	for _, e := range d.entries {
+		if e.msgid != "" || e.msgstrStart < 0 {
+			continue
+		}
+
+		var fields []string
+		langIdx, pfIdx := -1, -1
+		for _, line := range strings.SplitAfter(e.msgstr[0], "\n") {
+			if line == "" {
+				continue
+			}
+			switch name, _, _ := strings.Cut(line, ":"); strings.TrimSpace(name) {
+			case "Language":
+				langIdx = len(fields)
+			case "Plural-Forms":
+				pfIdx = len(fields)
+			}
+			fields = append(fields, line)
+		}
+
+		changed := false
+		set := func(idx int, name, value string) {
+			line := name + ": " + value + "\n"
+			if idx < 0 {
+				fields = append(fields, line)
+				changed = true
+			} else if fields[idx] != line {
+				fields[idx] = line
+				changed = true
+			}
+		}
+		sameLang := true
+		if toLang != "" {
+			if langIdx >= 0 {
+				_, lang, _ := strings.Cut(fields[langIdx], ":")
+				lang = strings.TrimSpace(lang)
+				sameLang = lang == "" || strings.EqualFold(strings.ReplaceAll(lang, "_", "-"), strings.ReplaceAll(toLang, "_", "-"))
+			}
+			set(langIdx, "Language", strings.ReplaceAll(toLang, "-", "_"))
+		}
+		if pfIdx >= 0 {
+			if _, value, _ := strings.Cut(fields[pfIdx], ":"); poNplurals(value) > 0 && sameLang {
+				pf = strings.TrimSpace(value)
+			} else {
+				set(pfIdx, "Plural-Forms", pf)
+			}
+		} else if plural {
+			set(pfIdx, "Plural-Forms", pf)
+		}
+
+		if changed {
+			lines := append(e.lines[:e.msgstrStart:e.msgstrStart], `msgstr ""`)
+			for _, field := range fields {
+				lines = append(lines, poQuote(field))
+			}
+			e.lines = append(lines, e.lines[e.msgstrEnd:]...)
+		}
+		break
+	}
+	return pf
+}
+
+// poNplurals 解析Plural-Forms中的nplurals，无效时（如POT模板的INTEGER）返回0
+func poNplurals(pf string) int {
+	m := poNpluralsRe.FindStringSubmatch(pf)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// poSingularForm0 Plural-Forms中msgstr[0]是否只用于n==1（法语等还包括0）。
+// 俄语等的msgstr[0]也用于21、31、101，不能用单数原文的译文
+func poSingularForm0(pf string) bool {
+	_, expr, ok := strings.Cut(strings.Join(strings.Fields(pf), ""), "plural=")
+	expr = strings.TrimLeft(expr, "(")
+	return ok && (strings.HasPrefix(expr, "n==1)") || strings.HasPrefix(expr, "n==1?") ||
+		strings.HasPrefix(expr, "n!=1)") || strings.HasPrefix(expr, "n>1)"))
+}
+
+func (d *poDoc) Texts() []string {
+	return d.texts
+}
+
+func (d *poDoc) Render(w io.Writer, translated []string) error {
+	writer := bufio.NewWriter(w)
+	for i, e := range d.entries {
+		lines := e.lines
+		if len(e.segs) > 0 {
+			lines = d.translatedLines(e, translated)
+		}
+		for j, line := range lines {
+			eol := d.eol
+			if !d.finalEOL && i == len(d.entries)-1 && j == len(lines)-1 {
+				eol = ""
+			}
+			if _, err := writer.WriteString(line + eol); err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Flush()
+}
+
+// translatedLines 换上译文并加上fuzzy标记，其余行原样保留
+func (d *poDoc) translatedLines(e *poEntry, translated []string) []string {
+	texts := make([]string, len(e.segs))
+	for i, seg := range e.segs {
+		texts[i] = restoreText(translated[seg], d.placeholders[seg])
+	}
+
+	var msgstr []string
+	if len(texts) == 1 {
+		msgstr = poMsgLines("msgstr", texts[0])
+	} else {
+		for n := 0; n < d.nplurals; n++ {
+			// 只有一种复数形式的语言，以及msgstr[0]也用于21、31等的语言（如俄语），用复数原文的译文
+			text := texts[1]
+			if n == 0 && d.nplurals > 1 && d.singular {
+				text = texts[0]
+			}
+			msgstr = append(msgstr, poMsgLines("msgstr["+strconv.Itoa(n)+"]", text)...)
+		}
+	}
+
+	var lines []string
+	if e.flags < 0 {
+		// "#,"放在其他注释之后、msgctxt/msgid之前
+		insert := 0
+		for insert < len(e.lines) && strings.HasPrefix(e.lines[insert], "#") {
+			insert++
+		}
+		lines = append(lines, e.lines[:insert]...)
+		lines = append(lines, "#, fuzzy")
+		lines = append(lines, e.lines[insert:e.msgstrStart]...)
+	} else {
+		lines = append(lines, e.lines[:e.msgstrStart]...)
+		if !e.fuzzy {
+			lines[e.flags] = "#, fuzzy," + strings.TrimPrefix(e.lines[e.flags], "#,")
+		}
+	}
+	lines = append(lines, msgstr...)
+	return append(lines, e.lines[e.msgstrEnd:]...)
+}
+
+// poMsgLines 多行文本按gettext的惯例，首行为空串，之后每行一个字符串
+func poMsgLines(key, text string) []string {
+	parts := strings.SplitAfter(text, "\n")
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) <= 1 {
+		return []string{key + " " + poQuote(text)}
+	}
+	lines := []string{key + ` ""`}
+	for _, p := range parts {
+		lines = append(lines, poQuote(p))
+	}
+	return lines
+}
+
+func poQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func poUnquote(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, `"`)
+	s = strings.TrimSuffix(s, `"`)
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
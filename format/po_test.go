@@ -0,0 +1,166 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const poSrc = `# Translator comment
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+#: main.go:10
+msgctxt "menu"
+msgid "Open %s file"
+msgstr ""
+
+#, python-format
+msgid "Hello {name}"
+msgstr "已翻译"
+
+#, fuzzy
+msgid "Save"
+msgstr "旧译文"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] ""
+msgstr[1] ""
+
+#~ msgid "Old"
+#~ msgstr ""
+`
+
+func TestPO_Translate(t *testing.T) {
+	doc, err := ParsePO([]byte(poSrc), Options{ToLang: "ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Open {ID_0} file", "Save", "{ID_0} file", "{ID_0} files"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{"Открыть файл {ID_0}", "Сохранить", "{ID_0} файл", "{ID_0} файлов"})
+	for _, s := range []string{
+		"\"Plural-Forms: nplurals=3; plural=",
+		"#: main.go:10\n#, fuzzy\nmsgctxt \"menu\"\nmsgid \"Open %s file\"\nmsgstr \"Открыть файл %s\"\n",
+		"#, python-format\nmsgid \"Hello {name}\"\nmsgstr \"已翻译\"\n",
+		"#, fuzzy\nmsgid \"Save\"\nmsgstr \"Сохранить\"\n",
+		"#, fuzzy\nmsgid \"%d file\"\nmsgid_plural \"%d files\"\nmsgstr[0] \"%d файлов\"\nmsgstr[1] \"%d файлов\"\nmsgstr[2] \"%d файлов\"\n",
+		"#~ msgid \"Old\"\n#~ msgstr \"\"\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestPO_SinglePluralForm(t *testing.T) {
+	doc, err := ParsePO([]byte(poSrc), Options{ToLang: "ja"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := render(t, doc, []string{"{ID_0}を開く", "保存", "{ID_0}個のファイル", "{ID_0}個のファイル（複数）"})
+	if !strings.Contains(got, "msgstr[0] \"%d個のファイル（複数）\"\n\n") {
+		t.Errorf("expected a single plural form:\n%s", got)
+	}
+	if !strings.Contains(got, "\"Plural-Forms: nplurals=1; plural=0;\\n\"") {
+		t.Errorf("expected Plural-Forms in header:\n%s", got)
+	}
+}
+
+func TestPO_MultilineAndEscapes(t *testing.T) {
+	src := "msgid \"\"\n\"Line \\\"one\\\"\\n\"\n\"Line two\"\nmsgstr \"\"\n"
+	doc, err := ParsePO([]byte(src), Options{ToLang: "zh-CN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`Line "one"{ID_0}Line two`}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	got := render(t, doc, []string{`第“一”行{ID_0}第二行`})
+	wantOut := "#, fuzzy\nmsgid \"\"\n\"Line \\\"one\\\"\\n\"\n\"Line two\"\nmsgstr \"\"\n\"第“一”行\\n\"\n\"第二行\"\n"
+	if got != wantOut {
+		t.Errorf("rendered %q, want %q", got, wantOut)
+	}
+}
+
+func TestPO_POTHeader(t *testing.T) {
+	src := `# SOME DESCRIPTIVE TITLE.
+#, fuzzy
+msgid ""
+msgstr ""
+"Project-Id-Version: PACKAGE VERSION\n"
+"Language-Team: LANGUAGE <LL@li.org>\n"
+"Language: \n"
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=CHARSET\n"
+"Plural-Forms: nplurals=INTEGER; plural=EXPRESSION;\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] ""
+msgstr[1] ""
+`
+	doc, err := ParsePO([]byte(src), Options{ToLang: "ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := render(t, doc, []string{"{ID_0} файл", "{ID_0} файлов"})
+	if n := strings.Count(got, "Plural-Forms:"); n != 1 {
+		t.Errorf("expected one Plural-Forms header, got %d:\n%s", n, got)
+	}
+	for _, s := range []string{
+		"\"Language: ru\\n\"\n\"MIME-Version: 1.0\\n\"",
+		"\"Plural-Forms: nplurals=3; plural=",
+		"msgstr[2] \"%d файлов\"\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+	if strings.Contains(got, "INTEGER") {
+		t.Errorf("template Plural-Forms should be replaced:\n%s", got)
+	}
+}
+
+func TestPO_KeepPluralFormsOfSameLanguage(t *testing.T) {
+	src := "msgid \"\"\nmsgstr \"\"\n\"Language: fr_FR\\n\"\n\"Plural-Forms: nplurals=2; plural=(n > 1);\\n\"\n\n" +
+		"msgid \"%d file\"\nmsgid_plural \"%d files\"\nmsgstr[0] \"\"\nmsgstr[1] \"\"\n"
+	for lang, want := range map[string]string{
+		"fr-FR": "\"Language: fr_FR\\n\"\n\"Plural-Forms: nplurals=2; plural=(n > 1);\\n\"",
+		"ja":    "\"Language: ja\\n\"\n\"Plural-Forms: nplurals=1; plural=0;\\n\"",
+	} {
+		doc, err := ParsePO([]byte(src), Options{ToLang: lang})
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts := doc.Texts()
+		if got := render(t, doc, texts); !strings.Contains(got, want) {
+			t.Errorf("%s: rendered output missing %q:\n%s", lang, want, got)
+		}
+	}
+}
+
+func TestPO_PluralForm0(t *testing.T) {
+	src := "msgid \"%d file\"\nmsgid_plural \"%d files\"\nmsgstr[0] \"\"\nmsgstr[1] \"\"\n"
+	// 俄语的msgstr[0]也用于21、31等，不能用单数原文的译文
+	for lang, want := range map[string]string{
+		"ru": "msgstr[0] \"PLURAL\"\nmsgstr[1] \"PLURAL\"\nmsgstr[2] \"PLURAL\"\n",
+		"pl": "msgstr[0] \"ONE\"\nmsgstr[1] \"PLURAL\"\nmsgstr[2] \"PLURAL\"\n",
+		"de": "msgstr[0] \"ONE\"\nmsgstr[1] \"PLURAL\"\n",
+		"fr": "msgstr[0] \"ONE\"\nmsgstr[1] \"PLURAL\"\n",
+	} {
+		doc, err := ParsePO([]byte(src), Options{ToLang: lang})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := render(t, doc, []string{"ONE", "PLURAL"}); !strings.Contains(got, want) {
+			t.Errorf("%s: rendered output missing %q:\n%s", lang, want, got)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+)
+
+// VTT的说话人标签<v Bob>，说话人不同的字幕不合并
+var vttVoiceRe = regexp.MustCompile(`<v[ .][^>]*>`)
+
+// ParseSRT 解析SRT字幕，序号、时间轴原样保留，只翻译对白
+func ParseSRT(data []byte, opts Options) (Document, error) {
+	return parseCueBlocks(data), nil
+}
+
+// ParseVTT 解析WebVTT字幕，文件头、NOTE、STYLE、REGION块及时间轴原样保留，只翻译对白
+func ParseVTT(data []byte, opts Options) (Document, error) {
+	return parseCueBlocks(data), nil
+}
+
+// parseCueBlocks SRT和WebVTT都以空行分隔字幕块，含"-->"的时间轴行之后到空行为对白
+func parseCueBlocks(data []byte) *subtitleDoc {
+	lines, eol, finalEOL := splitLines(data)
+	d := &subtitleDoc{}
+	for i := 0; i < len(lines); i++ {
+		d.addRaw(lines[i] + eol)
+		if !strings.Contains(lines[i], "-->") {
+			continue
+		}
+
+		j := i + 1
+		for j < len(lines) && strings.TrimSpace(lines[j]) != "" {
+			j++
+		}
+		if j > i+1 {
+			d.addCue(lines[i+1:j], eol, vttVoiceRe.FindString(lines[i+1]))
+			d.addRaw(eol)
+		}
+		i = j - 1
+	}
+	if !finalEOL {
+		trimFinalEOL(d.parts, eol)
+	}
+	d.group()
+	return d
+}
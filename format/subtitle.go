@@ -0,0 +1,222 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// 一句话最多合并的字幕条数
+	maxCuesPerSentence = 4
+	// 拆分译文时，在目标位置附近这么多字符内优先选标点处断开
+	breakWindow = 6
+)
+
+var (
+	// 字幕首尾的样式标签，如{\an8}、<i>、<v Bob>
+	subLeadTagsRe  = regexp.MustCompile(`^(?:\s*(?:\{[^}]*\}|<[^>]+>))+\s*`)
+	subTrailTagsRe = regexp.MustCompile(`\s*(?:(?:\{[^}]*\}|</[^>]+>)\s*)+$`)
+	// 字幕中间的样式标签、VTT的时间标签、ASS的硬空格和换行\N、\n，以及多行对白的换行
+	subInlineRe = regexp.MustCompile(`\{[^}]*\}|</?[a-zA-Z][^>]*>|<\d[\d:.]*>|\\[hNn]|\{[iI][dD]_\d+\}|\r?\n`)
+)
+
+// cue 一条字幕的对白，首尾的样式标签原样保留
+type cue struct {
+	prefix string
+	text   string
+	suffix string
+	key    string // 只合并key相同的相邻字幕，如ASS的样式和说话人
+}
+
+// subtitleDoc 字幕文档：时间轴、序号等原样保留，对白按句合并后翻译
+type subtitleDoc struct {
+	parts        []part // seg为cues的下标
+	cues         []*cue
+	groups       [][]int // 合并成一句翻译的字幕下标
+	texts        []string
+	placeholders [][]string
+}
+
+func (d *subtitleDoc) addRaw(s string) {
+	if s == "" {
+		return
+	}
+	if n := len(d.parts); n > 0 && d.parts[n-1].seg < 0 {
+		d.parts[n-1].raw += s
+		return
+	}
+	d.parts = append(d.parts, part{raw: s, seg: -1})
+}
+
+// addCue 追加多行对白，各行以eol连接后一起翻译，换行用占位符保护以保留分行
+func (d *subtitleDoc) addCue(lines []string, eol, key string) {
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	text := strings.Join(lines, eol)
+	c := &cue{key: key}
+	c.prefix = subLeadTagsRe.FindString(text)
+	text = text[len(c.prefix):]
+	c.suffix = subTrailTagsRe.FindString(text)
+	c.text = text[:len(text)-len(c.suffix)]
+	if !hasLetter(subInlineRe.ReplaceAllString(c.text, "")) { // 没有对白，如只有音符
+		d.addRaw(c.prefix + c.text + c.suffix)
+		return
+	}
+
+	d.parts = append(d.parts, part{seg: len(d.cues)})
+	d.cues = append(d.cues, c)
+}
+
+// group 将没有以句末标点结尾的字幕与下一条合并，生成待翻译的文本
+func (d *subtitleDoc) group() {
+	var curr []int
+	for i, c := range d.cues {
+		if len(curr) > 0 {
+			prev := d.cues[curr[len(curr)-1]]
+			if prev.key != c.key || isSentenceEnd(prev.text) || strings.HasPrefix(c.text, "-") ||
+				len(curr) >= maxCuesPerSentence {
+				d.groups = append(d.groups, curr)
+				curr = nil
+			}
+		}
+		curr = append(curr, i)
+	}
+	if len(curr) > 0 {
+		d.groups = append(d.groups, curr)
+	}
+
+	for _, g := range d.groups {
+		lines := make([]string, len(g))
+		for i, idx := range g {
+			lines[i] = d.cues[idx].text
+		}
+		text, originals := protectText(joinLines(lines), subInlineRe)
+		d.texts = append(d.texts, text)
+		d.placeholders = append(d.placeholders, originals)
+	}
+}
+
+func (d *subtitleDoc) Texts() []string {
+	return d.texts
+}
+
+// RenderLang 字幕中没有语言标记，与Render相同
+func (d *subtitleDoc) RenderLang(w io.Writer, translated []string, toLang string) error {
+	return d.Render(w, translated)
+}
+
+func (d *subtitleDoc) Render(w io.Writer, translated []string) error {
+	cueTexts := make([]string, len(d.cues))
+	for i, g := range d.groups {
+		weights := make([]int, len(g))
+		for j, idx := range g {
+			weights[j] = utf8.RuneCountInString(d.cues[idx].text)
+		}
+		for j, s := range splitByWeights(translated[i], weights) {
+			// 先合并译文中的空白，再还原占位符，保留原文的换行
+			cueTexts[g[j]] = restoreText(strings.Join(strings.Fields(s), " "), d.placeholders[i])
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	for _, p := range d.parts {
+		s := p.raw
+		if p.seg >= 0 {
+			c := d.cues[p.seg]
+			s = c.prefix + cueTexts[p.seg] + c.suffix
+		}
+		if _, err := writer.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// isSentenceEnd 文本是否以句末标点结尾，忽略末尾的引号和括号
+func isSentenceEnd(text string) bool {
+	text = strings.TrimRight(text, ` "'”’)]）」』`)
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return strings.ContainsRune(".!?。！？…♪", r)
+}
+
+// splitByWeights 按原文各条字幕的长度比例拆分译文，尽量在标点、空格处断开，不拆开占位符
+func splitByWeights(text string, weights []int) []string {
+	if len(weights) == 1 {
+		return []string{text}
+	}
+
+	// 占位符作为一个整体
+	var tokens []string
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringIndex(text, -1) {
+		for _, r := range text[last:loc[0]] {
+			tokens = append(tokens, string(r))
+		}
+		tokens = append(tokens, text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	for _, r := range text[last:] {
+		tokens = append(tokens, string(r))
+	}
+
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	result := make([]string, 0, len(weights))
+	start, acc := 0, 0
+	for _, w := range weights[:len(weights)-1] {
+		acc += w
+		target := len(tokens) * acc / max(total, 1)
+		cut := bestBreak(tokens, start, target)
+		result = append(result, strings.TrimSpace(strings.Join(tokens[start:cut], "")))
+		start = cut
+	}
+	return append(result, strings.TrimSpace(strings.Join(tokens[start:], "")))
+}
+
+// bestBreak 在tokens[start:]中选离target最近的断开位置，标点后优先，其次空格，再次中日韩文字之间
+func bestBreak(tokens []string, start, target int) int {
+	best, bestScore := -1, 0
+	for pos := start + 1; pos < len(tokens); pos++ {
+		score := abs(pos - target)
+		switch prev, next := tokens[pos-1], tokens[pos]; {
+		case isBreakPunct(prev):
+		case prev == " " || next == " ":
+			score += breakWindow / 2
+		case isCJKToken(prev) && isCJKToken(next):
+			score += breakWindow
+		default:
+			continue
+		}
+		if best < 0 || score < bestScore {
+			best, bestScore = pos, score
+		}
+	}
+	if best < 0 {
+		return min(max(target, start), len(tokens))
+	}
+	return best
+}
+
+func isBreakPunct(token string) bool {
+	r, _ := utf8.DecodeRuneInString(token)
+	return len(token) == utf8.RuneLen(r) && unicode.IsPunct(r) && !strings.ContainsRune(`"'“‘(（「『`, r)
+}
+
+func isCJKToken(token string) bool {
+	r, _ := utf8.DecodeRuneInString(token)
+	return len(token) == utf8.RuneLen(r) && isCJK(r)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,150 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSRT_MergeAndRedistribute(t *testing.T) {
+	src := `1
+00:00:01,000 --> 00:00:02,000
+<i>This is a sentence that</i>
+
+2
+00:00:02,000 --> 00:00:04,000
+continues here.
+
+3
+00:00:05,000 --> 00:00:06,000
+{\an8}Second one!
+- Yes.
+
+4
+00:00:07,000 --> 00:00:08,000
+♪
+`
+	doc, err := ParseSRT([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"This is a sentence that continues here.", "Second one!{ID_0}- Yes."}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{"这是一个句子，延续到这里。", "第二句！{ID_0}- 是的。"})
+	wantOut := `1
+00:00:01,000 --> 00:00:02,000
+<i>这是一个句子，</i>
+
+2
+00:00:02,000 --> 00:00:04,000
+延续到这里。
+
+3
+00:00:05,000 --> 00:00:06,000
+{\an8}第二句！
+- 是的。
+
+4
+00:00:07,000 --> 00:00:08,000
+♪
+`
+	if got != wantOut {
+		t.Errorf("rendered:\n%s\nwant:\n%s", got, wantOut)
+	}
+}
+
+func TestVTT_KeepsHeaderAndNotes(t *testing.T) {
+	src := "WEBVTT\n\nNOTE a comment\n\nintro\n00:01.000 --> 00:02.000 align:start\n<v Bob>Hello <b>there</b>.\n"
+	doc, err := ParseVTT([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Hello {ID_0}there{ID_1}."}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	got := render(t, doc, upper(doc.Texts()))
+	wantOut := "WEBVTT\n\nNOTE a comment\n\nintro\n00:01.000 --> 00:02.000 align:start\n<v Bob>HELLO <b>THERE</b>.\n"
+	if got != wantOut {
+		t.Errorf("rendered %q, want %q", got, wantOut)
+	}
+}
+
+func TestASS_Dialogue(t *testing.T) {
+	src := `[Script Info]
+Title: Demo
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Comment: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,not translated
+Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\i1}Hello, world,\Nhow are you?
+Dialogue: 0,0:00:03.00,0:00:04.00,Sign,,0,0,0,,{\p1}m 0 0 l 100 0
+Dialogue: 0,0:00:05.00,0:00:06.00,Default,,0,0,0,,I am
+Dialogue: 0,0:00:06.00,0:00:07.00,Sign,,0,0,0,,EXIT
+`
+	doc, err := ParseASS([]byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Hello, world,{ID_0}how are you?", "I am", "EXIT"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, upper(doc.Texts()))
+	for _, s := range []string{
+		"Comment: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,not translated\n",
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\\i1}HELLO, WORLD,\\NHOW ARE YOU?\n",
+		"Dialogue: 0,0:00:03.00,0:00:04.00,Sign,,0,0,0,,{\\p1}m 0 0 l 100 0\n",
+		"Dialogue: 0,0:00:06.00,0:00:07.00,Sign,,0,0,0,,EXIT\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestSplitByWeights(t *testing.T) {
+	tests := []struct {
+		text    string
+		weights []int
+		want    []string
+	}{
+		{"one two three four", []int{1, 1}, []string{"one two", "three four"}},
+		{"你好，世界", []int{1, 1}, []string{"你好，", "世界"}},
+		{"a {ID_0} b", []int{1, 1, 1}, []string{"a", "{ID_0}", "b"}},
+	}
+	for _, tt := range tests {
+		if got := splitByWeights(tt.text, tt.weights); !slices.Equal(got, tt.want) {
+			t.Errorf("splitByWeights(%q, %v) = %q, want %q", tt.text, tt.weights, got, tt.want)
+		}
+	}
+}
+
+func TestSubtitle_LineBreaks(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:02,000\r\nFirst line\r\nSecond line\r\n"
+	doc, err := ParseSRT([]byte(srt), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"First line{ID_0}Second line"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	want := "1\r\n00:00:01,000 --> 00:00:02,000\r\nFIRST LINE\r\nSECOND LINE\r\n"
+	if got := render(t, doc, upper(doc.Texts())); got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+
+	ass := "[Events]\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,First line\\NSecond line\n"
+	doc, err = ParseASS([]byte(ass), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "[Events]\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,FIRST LINE\\NSECOND LINE\n"
+	if got := render(t, doc, upper(doc.Texts())); got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
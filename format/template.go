@@ -0,0 +1,262 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/smilingpoplar/translate/util"
+)
+
+// 译文中的占位符，容忍大小写和花括号内的空白
+var placeholderRe = regexp.MustCompile(`(?i)\{\s*id_(\d+)\s*\}`)
+
+// part 文档的一段：seg<0时原样输出raw，否则输出第seg个片段的译文
+type part struct {
+	raw string
+	seg int
+}
+
+// langSeg 输出目标语言的part，渲染时才确定目标语言，见Reusable
+const langSeg = -2
+
+// template 由原样保留的内容和待翻译的片段交替组成，各格式解析时依次追加
+type template struct {
+	parts        []part
+	texts        []string   // 待翻译的片段，不应翻译的内容已换成占位符
+	placeholders [][]string // 各片段中占位符{ID_n}对应的原文
+	protect      *regexp.Regexp
+	singleLine   bool // 译文中的换行替换为空格，用于不能跨行的片段；占位符的原文不受影响
+}
+
+func (t *template) addRaw(s string) {
+	if s == "" {
+		return
+	}
+	if n := len(t.parts); n > 0 && t.parts[n-1].seg < 0 {
+		t.parts[n-1].raw += s
+		return
+	}
+	t.parts = append(t.parts, part{raw: s, seg: -1})
+}
+
+// addText 追加待翻译的文本，首尾空白原样保留；没有可翻译的文字时整段原样保留
+func (t *template) addText(s string) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		t.addRaw(s)
+		return
+	}
+	start := strings.Index(s, trimmed)
+	text, originals := protectText(trimmed, t.protect)
+	if !hasLetter(placeholderRe.ReplaceAllString(text, "")) {
+		t.addRaw(s)
+		return
+	}
+
+	t.addRaw(s[:start])
+	t.parts = append(t.parts, part{seg: len(t.texts)})
+	t.texts = append(t.texts, text)
+	t.placeholders = append(t.placeholders, originals)
+	t.addRaw(s[start+len(trimmed):])
+}
+
+func (t *template) Texts() []string {
+	return t.texts
+}
+
+// RenderLang 模板中没有语言标记，与Render相同
+func (t *template) RenderLang(w io.Writer, translated []string, toLang string) error {
+	return t.Render(w, translated)
+}
+
+func (t *template) Render(w io.Writer, translated []string) error {
+	writer := bufio.NewWriter(w)
+	for _, p := range t.parts {
+		s := p.raw
+		if p.seg >= 0 {
+			s = translated[p.seg]
+			if t.singleLine { // 先于还原占位符，占位符的原文可以含有换行，如Markdown的硬换行
+				s = strings.Join(strings.Fields(s), " ")
+			}
+			s = restoreText(s, t.placeholders[p.seg])
+		}
+		if _, err := writer.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// splitLines 按行拆分，返回行、换行符（\n或\r\n），以及末尾是否有换行
+func splitLines(data []byte) ([]string, string, bool) {
+	src := string(data)
+	eol := "\n"
+	if strings.Contains(src, "\r\n") {
+		eol = "\r\n"
+		src = strings.ReplaceAll(src, "\r\n", "\n")
+	}
+	finalEOL := strings.HasSuffix(src, "\n")
+	src = strings.TrimSuffix(src, "\n")
+	if src == "" && !finalEOL {
+		return nil, eol, false
+	}
+	return strings.Split(src, "\n"), eol, finalEOL
+}
+
+// appendRaw 追加原样输出的内容，与前面的原样内容合并
+func appendRaw(parts []part, s string) []part {
+	if s == "" {
+		return parts
+	}
+	if n := len(parts); n > 0 && parts[n-1].seg == -1 {
+		parts[n-1].raw += s
+		return parts
+	}
+	return append(parts, part{raw: s, seg: -1})
+}
+
+// trimFinalEOL 原文末尾没有换行时，去掉逐行输出时多出的换行
+func trimFinalEOL(parts []part, eol string) {
+	if n := len(parts); n > 0 && parts[n-1].seg < 0 {
+		parts[n-1].raw = strings.TrimSuffix(parts[n-1].raw, eol)
+	}
+}
+
+// protectText 将re匹配的内容换成占位符{ID_n}，返回替换后的文本和各占位符的原文。
+// 以"]"开头的匹配（链接地址）保留"]"，让链接文字保持完整
+func protectText(text string, re *regexp.Regexp) (string, []string) {
+	if re == nil {
+		return text, nil
+	}
+	var originals []string
+	protected := re.ReplaceAllStringFunc(text, func(m string) string {
+		prefix := ""
+		if strings.HasPrefix(m, "]") {
+			prefix, m = "]", m[1:]
+		}
+		originals = append(originals, m)
+		return prefix + util.GeneratePlaceholder(len(originals)-1)
+	})
+	return protected, originals
+}
+
+// restoreText 将译文中的占位符换回原文，无法识别的占位符原样保留
+func restoreText(text string, originals []string) string {
+	if len(originals) == 0 {
+		return text
+	}
+	return placeholderRe.ReplaceAllStringFunc(text, func(m string) string {
+		id, err := strconv.Atoi(placeholderRe.FindStringSubmatch(m)[1])
+		if err != nil || id >= len(originals) {
+			return m
+		}
+		return originals[id]
+	})
+}
+
+// restoreEscaped 用escape转义译文中的文本，占位符换回原文，无法识别的占位符转义后保留
+func restoreEscaped(text string, originals []string, escape func(string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(escape(text[last:loc[0]]))
+		last = loc[1]
+		if id, err := strconv.Atoi(text[loc[2]:loc[3]]); err == nil && id < len(originals) {
+			sb.WriteString(originals[id])
+		} else {
+			sb.WriteString(escape(text[loc[0]:loc[1]]))
+		}
+	}
+	sb.WriteString(escape(text[last:]))
+	return sb.String()
+}
+
+// protector 逐段生成带占位符的文本，相邻的不翻译内容合并成一个占位符
+type protector struct {
+	sb        strings.Builder
+	originals []string
+	pending   strings.Builder
+}
+
+// keep 不翻译的内容
+func (p *protector) keep(s string) {
+	p.pending.WriteString(s)
+}
+
+// text 待翻译的文字
+func (p *protector) text(s string) {
+	p.flush()
+	p.sb.WriteString(s)
+}
+
+func (p *protector) flush() {
+	if p.pending.Len() == 0 {
+		return
+	}
+	p.sb.WriteString(util.GeneratePlaceholder(len(p.originals)))
+	p.originals = append(p.originals, p.pending.String())
+	p.pending.Reset()
+}
+
+func (p *protector) result() (string, []string) {
+	p.flush()
+	return p.sb.String(), p.originals
+}
+
+// escaped 处理含反斜杠转义的文字：literal中的字符转义后按原字符翻译，其余转义（\n、\u2026等）
+// 和spec匹配的格式符换成占位符
+func (p *protector) escaped(s string, spec *regexp.Regexp, literal string) {
+	for i := 0; i < len(s); {
+		if s[i] == '\\' && i+1 < len(s) {
+			n := 2
+			switch c := s[i+1]; {
+			case strings.IndexByte(literal, c) >= 0:
+				p.text(s[i+1 : i+2])
+				i += 2
+				continue
+			case (c == 'u' || c == 'U') && i+6 <= len(s) && isHex(s[i+2:i+6]):
+				n = 6
+			}
+			p.keep(s[i : i+n])
+			i += n
+			continue
+		}
+		if loc := spec.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 && loc[1] > 0 {
+			p.keep(s[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+		// 普通文字直到下一个反斜杠或格式符
+		j := len(s)
+		if loc := spec.FindStringIndex(s[i+1:]); loc != nil {
+			j = i + 1 + loc[0]
+		}
+		if k := strings.IndexByte(s[i+1:j], '\\'); k >= 0 {
+			j = i + 1 + k
+		}
+		p.text(s[i:j])
+		i = j
+	}
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLetter(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,377 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/smilingpoplar/translate/util"
+)
+
+// 机器翻译的译文状态
+const (
+	xliff12State = "needs-review-translation"
+	xliff20State = "translated"
+)
+
+var (
+	// 含可翻译文字的行内元素，开始和结束标签各换成一个占位符；
+	// 其余行内代码（x、bx、ex、ph、bpt、ept、it、sc、ec、cp等）整个元素换成一个占位符
+	xliffPairedTags = map[string]bool{"g": true, "pc": true, "mrk": true}
+
+	xmlEscaper     = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+	// 开始标签中的目标语言属性，子匹配为双引号或单引号内的属性值
+	xliffLangAttrRe = map[string]*regexp.Regexp{
+		"trgLang":         regexp.MustCompile(`\strgLang\s*=\s*(?:"([^"]*)"|'([^']*)')`),
+		"target-language": regexp.MustCompile(`\starget-language\s*=\s*(?:"([^"]*)"|'([^']*)')`),
+	}
+)
+
+// xliffUnit 待填写的译文：prefix、suffix为<target>的开始和结束标签，lead、trail为原文首尾的空白
+type xliffUnit struct {
+	prefix    string
+	suffix    string
+	originals []string
+	lead      string
+	trail     string
+}
+
+type xliffDoc struct {
+	parts []part // seg为units的下标，langSeg为目标语言属性值
+	units []*xliffUnit
+	texts []string
+	lang  string
+}
+
+// xliffState 解析过程中当前翻译单元（1.2的trans-unit、2.0的segment）的状态
+type xliffState struct {
+	segTag      [2]int // 2.0中<segment>开始标签的范围
+	segName     string
+	segAttrs    []xml.Attr
+	translate   bool
+	state       string // 1.2为target的state，2.0为segment的state
+	srcStart    int    // <source>开始标签的起点
+	srcInner    [2]int // <source>内容的范围
+	srcEnd      int
+	tgtStart    int // <target>元素的范围，没有时为-1
+	tgtEnd      int
+	tgtInner    [2]int
+	tgtAttrs    []xml.Attr
+	tgtSelfStop bool
+}
+
+// ParseXLIFF 解析XLIFF 1.2/2.0，为没有译文或需要翻译的<source>填写<target>。
+// translate="no"的单元不翻译，行内代码换成占位符，其余内容原样保留
+func ParseXLIFF(data []byte, opts Options) (Document, error) {
+	d := &xliffDoc{lang: opts.ToLang}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	version := ""
+	translate := []bool{true} // 按元素嵌套继承translate属性
+	skip := 0                 // 在alt-trans、ignorable等元素内，其中的source、target不处理
+	var cur *xliffState
+	last := 0 // data[last:]尚未写入parts
+
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing xliff: %w", err)
+		}
+		end := int(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			tr := translate[len(translate)-1]
+			if v := xmlAttr(t.Attr, "translate"); v != "" {
+				tr = v != "no"
+			}
+			translate = append(translate, tr)
+
+			name := t.Name.Local
+			if skip > 0 || name == "alt-trans" || name == "ignorable" {
+				skip++
+				continue
+			}
+			switch {
+			case name == "xliff":
+				version = xmlAttr(t.Attr, "version")
+				if strings.HasPrefix(version, "2") && opts.ToLang != "" {
+					d.setLangAttr(data[last:start], data[start:end], "trgLang")
+					last = end
+				}
+			case name == "file" && !strings.HasPrefix(version, "2"):
+				if opts.ToLang != "" {
+					d.setLangAttr(data[last:start], data[start:end], "target-language")
+					last = end
+				}
+			case name == "trans-unit" || name == "segment":
+				cur = &xliffState{translate: tr, tgtStart: -1}
+				if name == "segment" {
+					cur.state = xmlAttr(t.Attr, "state")
+					cur.segTag, cur.segName, cur.segAttrs = [2]int{start, end}, xmlName(t.Name), t.Attr
+				}
+			case cur != nil && name == "source":
+				cur.srcStart, cur.srcInner[0] = start, end
+			case cur != nil && name == "target":
+				cur.tgtStart, cur.tgtInner[0], cur.tgtAttrs = start, end, t.Attr
+				if !strings.HasPrefix(version, "2") {
+					cur.state = xmlAttr(t.Attr, "state")
+				}
+			}
+
+		case xml.EndElement:
+			translate = translate[:len(translate)-1]
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch name := t.Name.Local; {
+			case cur != nil && name == "source":
+				cur.srcInner[1], cur.srcEnd = start, end
+			case cur != nil && name == "target":
+				cur.tgtInner[1], cur.tgtEnd = start, end
+				cur.tgtSelfStop = start == end // <target/>
+			case cur != nil && (name == "trans-unit" || name == "segment"):
+				if d.needsTarget(cur, strings.HasPrefix(version, "2"), data) {
+					if err := d.addUnit(data, &last, cur, strings.HasPrefix(version, "2")); err != nil {
+						return nil, err
+					}
+				}
+				cur = nil
+			}
+		}
+	}
+	d.addRaw(string(data[last:]))
+	return d, nil
+}
+
+// needsTarget 可翻译、有原文，且没有译文或状态表明需要翻译。
+// 1.2中state为new、needs-translation的译文重新翻译；2.0只翻译state为initial的segment
+func (d *xliffDoc) needsTarget(s *xliffState, v2 bool, data []byte) bool {
+	if !s.translate || s.srcEnd == 0 {
+		return false
+	}
+	empty := s.tgtStart < 0 || s.tgtSelfStop || len(bytes.TrimSpace(data[s.tgtInner[0]:s.tgtInner[1]])) == 0
+	if v2 {
+		return empty && (s.state == "" || s.state == "initial")
+	}
+	if s.state == "new" || s.state == "needs-translation" {
+		return true
+	}
+	return empty && s.state == ""
+}
+
+func (d *xliffDoc) addUnit(data []byte, last *int, s *xliffState, v2 bool) error {
+	inline, originals, err := xliffInline(string(data[s.srcInner[0]:s.srcInner[1]]))
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(inline)
+	if !hasLetter(placeholderRe.ReplaceAllString(text, "")) {
+		return nil
+	}
+	lead := inline[:strings.Index(inline, text)]
+
+	// 1.2的state在target上，2.0的state在segment上
+	attrs := s.tgtAttrs
+	if v2 {
+		segAttrs := setXMLAttr(s.segAttrs, "state", xliff20State)
+		d.addRaw(string(data[*last:s.segTag[0]]) + "<" + s.segName + formatXMLAttrs(segAttrs) + ">")
+		*last = s.segTag[1]
+	} else {
+		attrs = setXMLAttr(attrs, "state", xliff12State)
+	}
+	u := &xliffUnit{
+		prefix: "<target" + formatXMLAttrs(attrs) + ">", suffix: "</target>", originals: originals,
+		lead: lead, trail: inline[len(lead)+len(text):],
+	}
+
+	if s.tgtStart >= 0 { // 替换原有的<target>
+		d.addRaw(string(data[*last:s.tgtStart]) + u.prefix)
+		*last = s.tgtEnd
+	} else { // 在</source>后插入，与<source>同样缩进
+		d.addRaw(string(data[*last:s.srcEnd]) + lineIndent(data, s.srcStart) + u.prefix)
+		*last = s.srcEnd
+	}
+	d.parts = append(d.parts, part{seg: len(d.units)})
+	d.units = append(d.units, u)
+	d.texts = append(d.texts, text)
+	d.addRaw(u.suffix)
+	return nil
+}
+
+// xliffInline 提取<source>内容的文本，行内代码换成占位符，首尾空白由调用方处理
+func xliffInline(inner string) (string, []string, error) {
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	dec.Strict = false
+	var sb strings.Builder
+	var originals []string
+	placeholder := func(s string) {
+		sb.WriteString(util.GeneratePlaceholder(len(originals)))
+		originals = append(originals, s)
+	}
+
+	atomic := 0 // 在行内代码中的嵌套深度
+	atomicStart := 0
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("error parsing xliff source: %w", err)
+		}
+		end := int(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if atomic > 0 {
+				atomic++
+			} else if xliffPairedTags[t.Name.Local] && xmlAttr(t.Attr, "translate") != "no" {
+				placeholder(inner[start:end])
+			} else {
+				atomic, atomicStart = 1, start
+			}
+		case xml.EndElement:
+			if atomic > 0 {
+				if atomic--; atomic == 0 {
+					placeholder(inner[atomicStart:end])
+				}
+			} else if start < end {
+				placeholder(inner[start:end])
+			}
+		case xml.CharData:
+			if atomic > 0 {
+				continue
+			}
+			// 原文中形如占位符的文本也保护起来
+			text, last := string(t), 0
+			for _, loc := range placeholderRe.FindAllStringIndex(text, -1) {
+				sb.WriteString(text[last:loc[0]])
+				placeholder(xmlEscaper.Replace(text[loc[0]:loc[1]]))
+				last = loc[1]
+			}
+			sb.WriteString(text[last:])
+		default: // 注释、处理指令
+			if atomic == 0 {
+				placeholder(inner[start:end])
+			}
+		}
+	}
+	return sb.String(), originals, nil
+}
+
+func (d *xliffDoc) addRaw(s string) {
+	d.parts = appendRaw(d.parts, s)
+}
+
+// setLangAttr 将开始标签tag中的目标语言属性name改为渲染时的目标语言，没有该属性时在标签末尾加上
+func (d *xliffDoc) setLangAttr(before, tag []byte, name string) {
+	if m := xliffLangAttrRe[name].FindSubmatchIndex(tag); m != nil {
+		valStart, valEnd := m[2], m[3]
+		if valStart < 0 { // 单引号
+			valStart, valEnd = m[4], m[5]
+		}
+		d.addRaw(string(before) + string(tag[:valStart]))
+		d.parts = append(d.parts, part{seg: langSeg})
+		d.addRaw(string(tag[valEnd:]))
+		return
+	}
+
+	end := ">"
+	if bytes.HasSuffix(tag, []byte("/>")) {
+		end = "/>"
+	}
+	d.addRaw(string(before) + strings.TrimSuffix(string(tag), end) + " " + name + `="`)
+	d.parts = append(d.parts, part{seg: langSeg})
+	d.addRaw(`"` + end)
+}
+
+func (d *xliffDoc) Texts() []string {
+	return d.texts
+}
+
+func (d *xliffDoc) Render(w io.Writer, translated []string) error {
+	return d.RenderLang(w, translated, d.lang)
+}
+
+func (d *xliffDoc) RenderLang(w io.Writer, translated []string, toLang string) error {
+	writer := bufio.NewWriter(w)
+	for _, p := range d.parts {
+		s := p.raw
+		switch {
+		case p.seg >= 0:
+			u := d.units[p.seg]
+			s = xmlEscaper.Replace(u.lead) + restoreEscaped(translated[p.seg], u.originals, xmlEscaper.Replace) +
+				xmlEscaper.Replace(u.trail)
+		case p.seg == langSeg:
+			s = xmlAttrEscaper.Replace(toLang)
+		}
+		if _, err := writer.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+func xmlAttr(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func setXMLAttr(attrs []xml.Attr, name, value string) []xml.Attr {
+	result := make([]xml.Attr, 0, len(attrs)+1)
+	for _, a := range attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			continue
+		}
+		result = append(result, a)
+	}
+	return append(result, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+func formatXMLAttrs(attrs []xml.Attr) string {
+	var sb strings.Builder
+	for _, a := range attrs {
+		sb.WriteString(" " + xmlName(a.Name) + `="` + xmlAttrEscaper.Replace(a.Value) + `"`)
+	}
+	return sb.String()
+}
+
+// xmlName RawToken不解析命名空间，Space为原文中的前缀
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// lineIndent 返回换行符和data[pos]所在行的缩进
+func lineIndent(data []byte, pos int) string {
+	lineStart := bytes.LastIndexByte(data[:pos], '\n') + 1
+	indent := data[lineStart:pos]
+	if len(bytes.TrimSpace(indent)) > 0 {
+		return ""
+	}
+	eol := "\n"
+	if lineStart >= 2 && data[lineStart-2] == '\r' {
+		eol = "\r\n"
+	}
+	return eol + string(indent)
+}
@@ -0,0 +1,159 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestXLIFF12(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file source-language="en" datatype="plaintext" original="app">
+    <body>
+      <trans-unit id="1">
+        <source>Hello <g id="1">bold</g> world<x id="2"/> &amp; more</source>
+      </trans-unit>
+      <trans-unit id="2" translate="no">
+        <source>BrandName</source>
+      </trans-unit>
+      <trans-unit id="3">
+        <source>Done</source>
+        <target state="final">完成</target>
+      </trans-unit>
+      <trans-unit id="4">
+        <source>Cancel</source>
+        <target state="new">旧</target>
+        <alt-trans><source>Cancel</source><target>取消</target></alt-trans>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`
+	doc, err := ParseXLIFF([]byte(src), Options{ToLang: "zh-CN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Hello {ID_0}bold{ID_1} world{ID_2} & more", "Cancel"}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{"你好 {ID_0}粗体{ID_1} 世界{ID_2} & 更多", "取消"})
+	for _, s := range []string{
+		`<file source-language="en" datatype="plaintext" original="app" target-language="zh-CN">`,
+		"<source>Hello <g id=\"1\">bold</g> world<x id=\"2\"/> &amp; more</source>\n" +
+			`        <target state="needs-review-translation">你好 <g id="1">粗体</g> 世界<x id="2"/> &amp; 更多</target>`,
+		"<source>BrandName</source>\n      </trans-unit>",
+		`<target state="final">完成</target>`,
+		`<target state="needs-review-translation">取消</target>`,
+		`<alt-trans><source>Cancel</source><target>取消</target></alt-trans>`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestXLIFF20(t *testing.T) {
+	src := `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en">
+ <file id="f1">
+  <unit id="u1">
+   <segment>
+    <source>Click <pc id="1">here</pc><ph id="2"/>.</source>
+   </segment>
+   <ignorable><source> </source></ignorable>
+  </unit>
+  <unit id="u2">
+   <segment state="reviewed">
+    <source>Keep</source>
+    <target>保留</target>
+   </segment>
+  </unit>
+ </file>
+</xliff>`
+	doc, err := ParseXLIFF([]byte(src), Options{ToLang: "zh-CN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Click {ID_0}here{ID_1}{ID_2}."}
+	if got := doc.Texts(); !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	got := render(t, doc, []string{"点击{ID_0}这里{ID_1}{ID_2}。"})
+	for _, s := range []string{
+		`version="2.0" srcLang="en" trgLang="zh-CN">`,
+		"<segment state=\"translated\">\n    <source>Click <pc id=\"1\">here</pc><ph id=\"2\"/>.</source>\n" +
+			"    <target>点击<pc id=\"1\">这里</pc><ph id=\"2\"/>。</target>\n   </segment>",
+		"<segment state=\"reviewed\">\n    <source>Keep</source>\n    <target>保留</target>",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered output missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestXLIFFInline_LiteralPlaceholders(t *testing.T) {
+	text, originals, err := xliffInline("Use {id_1} and {id_2} here")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Use {ID_0} and {ID_1} here"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+	if want := []string{"{id_1}", "{id_2}"}; !slices.Equal(originals, want) {
+		t.Errorf("originals = %q, want %q", originals, want)
+	}
+}
+
+func TestXLIFF_KeepSourceWhitespace(t *testing.T) {
+	src := `<xliff version="1.2"><file source-language="en"><body>
+<trans-unit id="1"><source>  Hello <g id="1">world</g>
+  </source></trans-unit>
+</body></file></xliff>`
+	doc, err := ParseXLIFF([]byte(src), Options{ToLang: "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Texts(), []string{"Hello {ID_0}world{ID_1}"}; !slices.Equal(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+	got := render(t, doc, []string{"Hallo {ID_0}Welt{ID_1}"})
+	if want := "<target state=\"needs-review-translation\">  Hallo <g id=\"1\">Welt</g>\n  </target>"; !strings.Contains(got, want) {
+		t.Errorf("rendered output missing %q:\n%s", want, got)
+	}
+}
+
+func TestXLIFF_ReplaceTargetLanguage(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"1.2",
+			`<xliff version="1.2"><file source-language="en" target-language="de"><body>` +
+				`<trans-unit id="1"><source>Hello</source></trans-unit></body></file></xliff>`,
+			`<file source-language="en" target-language="fr">`,
+		},
+		{
+			"2.0",
+			`<xliff version="2.0" srcLang="en" trgLang = 'de'><file id="f"><unit id="1">` +
+				`<segment><source>Hello</source></segment></unit></file></xliff>`,
+			`<xliff version="2.0" srcLang="en" trgLang = 'fr'>`,
+		},
+	} {
+		doc, err := ParseXLIFF([]byte(tt.src), Options{ToLang: "fr"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := render(t, doc, []string{"Bonjour"})
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: rendered output missing %q:\n%s", tt.name, tt.want, got)
+		}
+		if strings.Contains(got, "de") {
+			t.Errorf("%s: rendered output still has the old target language:\n%s", tt.name, got)
+		}
+	}
+}
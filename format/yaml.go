@@ -0,0 +1,83 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 首个缩进行的缩进，输出时沿用
+var yamlIndentRe = regexp.MustCompile(`(?m)^( +)\S`)
+
+type yamlDoc struct {
+	root   *yaml.Node
+	indent int
+	nodes  []*yaml.Node // 各片段对应的标量节点
+	i18n   *i18nTexts
+}
+
+// ParseYAML 解析Rails等YAML资源文件，只翻译字符串值，键、注释和顺序保持不变。
+// 顶层只有一个语言代码的键（如en:）时改为目标语言；opts.Keys不为空时只翻译选中的键
+func ParseYAML(data []byte, opts Options) (Document, error) {
+	d := &yamlDoc{i18n: newI18nTexts(opts.Keys), indent: 2}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("error parsing yaml: %w", err)
+	}
+	d.root = &root
+	if m := yamlIndentRe.FindSubmatch(data); m != nil {
+		d.indent = len(m[1])
+	}
+
+	if len(root.Content) > 0 {
+		top := root.Content[0]
+		d.walk(top, nil) // 选择器按原来的键匹配
+		if top.Kind == yaml.MappingNode && len(top.Content) == 2 && opts.ToLang != "" {
+			if _, ok := rootLocale([]string{top.Content[0].Value}); ok && top.Content[1].Kind == yaml.MappingNode {
+				top.Content[0].Value = opts.ToLang
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *yamlDoc) walk(n *yaml.Node, path []string) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			d.walk(n.Content[i+1], pathAppend(path, n.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			d.walk(c, pathAppend(path, i))
+		}
+	case yaml.ScalarNode:
+		if n.ShortTag() == "!!str" && d.i18n.add(path, n.Value) >= 0 {
+			d.nodes = append(d.nodes, n)
+		}
+	}
+}
+
+func (d *yamlDoc) Texts() []string {
+	return d.i18n.texts
+}
+
+func (d *yamlDoc) Render(w io.Writer, translated []string) error {
+	for i, n := range d.nodes {
+		n.Value = d.i18n.restore(i, translated[i])
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(d.indent)
+	if err := enc.Encode(d.root); err != nil {
+		return fmt.Errorf("error encoding yaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
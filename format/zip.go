@@ -0,0 +1,37 @@
+package format
+
+import (
+	"archive/zip"
+	"io"
+)
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// rewriteZip 按原有顺序重新打包：replaced中的文件重新压缩，其余文件原样复制
+func rewriteZip(w io.Writer, r *zip.Reader, replaced map[string][]byte) error {
+	zw := zip.NewWriter(w)
+	for _, f := range r.File {
+		content, ok := replaced[f.Name]
+		if !ok {
+			if err := zw.Copy(f); err != nil {
+				return err
+			}
+			continue
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: f.Modified})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,220 @@
+// Anthropic Messages API文档：
+// https://docs.anthropic.com/en/api/messages
+
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/llm"
+	"github.com/smilingpoplar/translate/translator/middleware"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+	"github.com/smilingpoplar/translate/util"
+)
+
+const (
+	apiVersion          = "2023-06-01"
+	defaultSystemPrompt = "You are a professional translation engine. Follow the user's output format strictly."
+	// 单次请求的超时时间，LLM生成较慢
+	requestTimeout = 3 * time.Minute
+	// 服务过载
+	statusOverloaded = 529
+)
+
+type Anthropic struct {
+	client    *http.Client
+	model     string
+	maxTokens int
+	system    string
+	handler   middleware.Handler
+	glossary  util.Glossary
+	onTrans   func([]string) error
+	Name      string
+	apiKey    string
+	baseURL   string
+	extraBody map[string]any
+	cache     *util.Cache
+}
+
+type option func(*Anthropic) error
+
+func New(sc *config.ServiceConfig, opts ...option) (*Anthropic, error) {
+	service := sc.Name
+	maxTokens, err := strconv.Atoi(sc.GetEnvValue("max-tokens"))
+	if err != nil || maxTokens <= 0 {
+		return nil, fmt.Errorf("error creating anthropic translator: invalid max-tokens %q", sc.GetEnvValue("max-tokens"))
+	}
+	system := sc.GetEnvValue("system-prompt")
+	if system == "" {
+		system = defaultSystemPrompt
+	}
+
+	a := &Anthropic{
+		client:    &http.Client{},
+		model:     sc.GetEnvValue("model"),
+		maxTokens: maxTokens,
+		system:    system,
+		Name:      service,
+		apiKey:    sc.GetEnvValue("api-key"),
+		baseURL:   strings.TrimSuffix(sc.GetEnvValue("base-url"), "/"),
+		extraBody: sc.GetExtraBody(),
+	}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, fmt.Errorf("error creating anthropic translator: %w", err)
+		}
+	}
+	if cache, err := util.NewCache(service, 20*time.Minute); err == nil {
+		a.cache = cache
+	} else {
+		return nil, fmt.Errorf("error creating anthropic translator: %w", err)
+	}
+
+	chain := middleware.Chain(
+		middleware.TextsLimit(2000),
+		middleware.OnTranslated(&a.onTrans),
+		middleware.PassThrough(),
+		middleware.Glossary(a.glossary),
+		middleware.Retry(8, 3),
+		middleware.Cache(a.cache),
+		middleware.RateLimit(sc.GetRpm()),
+		middleware.Concurrent(sc.GetMaxConcurrency()),
+	)
+	a.handler = chain(a.translate)
+
+	return a, nil
+}
+
+func WithProxy(proxy string) option {
+	return func(a *Anthropic) error {
+		return util.SetProxy(proxy, a.client)
+	}
+}
+
+func WithGlossary(glossary util.Glossary) option {
+	return func(a *Anthropic) error {
+		a.glossary = glossary
+		return nil
+	}
+}
+
+func (a *Anthropic) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	prompt, err := config.GetPrompt(texts, fromLang, toLang)
+	if err != nil {
+		return nil, fmt.Errorf("error translating: %w", err)
+	}
+
+	result, err := a.sendRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := llm.ParseTranslations(result, texts)
+	if err != nil {
+		return nil, fmt.Errorf("error translating: %w", err)
+	}
+	return parsed, nil
+}
+
+func (a *Anthropic) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return a.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (a *Anthropic) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	return a.handler(ctx, texts, fromLang, toLang)
+}
+
+func (a *Anthropic) OnTranslated(f func([]string) error) {
+	a.onTrans = f
+}
+
+func (a *Anthropic) Close() error {
+	return a.cache.Close()
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type messagesRequest struct {
+	Model     string    `json:"model"`
+	MaxTokens int       `json:"max_tokens"`
+	System    string    `json:"system,omitempty"`
+	Messages  []message `json:"messages"`
+}
+
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type messagesResponse struct {
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+}
+
+func (a *Anthropic) sendRequest(ctx context.Context, prompt string) (string, error) {
+	request := messagesRequest{
+		Model:     a.model,
+		MaxTokens: a.maxTokens,
+		System:    a.system,
+		Messages:  []message{{Role: "user", Content: prompt}},
+	}
+	reqJSON, err := util.MergeJSON(request, a.extraBody)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/messages", bytes.NewReader(reqJSON))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == statusOverloaded {
+		return "", transerrors.ErrTooManyRequests
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var response messagesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	if response.StopReason == "max_tokens" {
+		return "", fmt.Errorf("error making request: %w by max-tokens %d", transerrors.ErrTruncated, a.maxTokens)
+	}
+
+	var sb strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,84 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/util"
+)
+
+// newTestAnthropic 创建指向stand-in服务的Anthropic翻译器
+func newTestAnthropic(t *testing.T, handler http.HandlerFunc) *Anthropic {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv("TMPDIR", t.TempDir()) // 隔离缓存文件
+	t.Setenv("ANTHROPIC_BASE_URL", server.URL+"/v1")
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("ANTHROPIC_MODEL", "claude-test")
+	t.Setenv("ANTHROPIC_MAX_TOKENS", "1024")
+
+	sc := config.NewServiceConfig("anthropic")
+	if err := sc.ValidateEnvArgs(); err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(sc, WithGlossary(util.NewGlossary(map[string]string{"AWS": "AWS"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestTranslate(t *testing.T) {
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != apiVersion {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		var req messagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Model != "claude-test" || req.MaxTokens != 1024 || req.System != defaultSystemPrompt {
+			t.Errorf("unexpected request %+v", req)
+		}
+		if len(req.Messages) != 1 || !strings.Contains(req.Messages[0].Content, `"text":"use {ID_0}"`) {
+			t.Errorf("unexpected prompt %+v", req.Messages)
+		}
+
+		// 模型有时会把json包在代码块里
+		text := "```json\n" + `[{"id":0,"text":"使用 {ID_0}"},{"id":1,"text":"你好"}]` + "\n```"
+		fmt.Fprintf(w, `{"content":[{"type":"text","text":%q}],"stop_reason":"end_turn"}`, text)
+	})
+
+	got, err := a.Translate([]string{"use AWS", "hello"}, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "使用 AWS" || got[1] != "你好" {
+		t.Errorf("unexpected result %q", got)
+	}
+}
+
+func TestTranslate_APIError(t *testing.T) {
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	})
+
+	_, err := a.Translate([]string{"hello"}, "", "zh-CN")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401 error, got %v", err)
+	}
+}
@@ -1,32 +1,171 @@
 package translator
 
 import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+
 	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/anthropic"
+	"github.com/smilingpoplar/translate/translator/deepl"
+	"github.com/smilingpoplar/translate/translator/fallback"
+	"github.com/smilingpoplar/translate/translator/gemini"
 	"github.com/smilingpoplar/translate/translator/google"
+	"github.com/smilingpoplar/translate/translator/libretranslate"
+	"github.com/smilingpoplar/translate/translator/ollama"
 	"github.com/smilingpoplar/translate/translator/openai"
+	"github.com/smilingpoplar/translate/translator/router"
+	"github.com/smilingpoplar/translate/util"
 )
 
 const (
-	kGoogle = "google"
-	kOpenAI = "openai"
+	kGoogle    = "google"
+	kOpenAI    = "openai"
+	kDeepL     = "deepl"
+	kAnthropic = "anthropic"
+	kGemini    = "gemini"
+	kOllama    = "ollama"
+	kLibre     = "libretranslate"
+	kAzure     = "azure"
+	kFallback  = "fallback"
+	kRouter    = "router"
 )
 
-func GetTranslator(service, proxy string, glossary map[string]string) (Translator, error) {
+func GetTranslator(service, proxy string, glossary util.Glossary) (Translator, error) {
+	return getTranslator(service, proxy, glossary, nil)
+}
+
+// parents为正在构造的组合服务，用于发现循环引用
+func getTranslator(service, proxy string, glossary util.Glossary, parents []string) (Translator, error) {
+	if slices.Contains(parents, service) {
+		return nil, fmt.Errorf("circular service reference: %s -> %s", parents, service)
+	}
+
 	sc := config.NewServiceConfig(service)
 	var trans Translator
 	var err error
 	if sc.Name == kGoogle {
 		trans, err = google.New(google.WithProxy(proxy), google.WithGlossary(glossary))
-	} else if sc.Name == kOpenAI || sc.Type == kOpenAI {
+	} else if sc.Type == kFallback {
+		trans, err = getTranslatorFallback(sc, proxy, glossary, append(slices.Clone(parents), service))
+	} else if sc.Type == kRouter {
+		trans, err = getTranslatorRouter(sc, proxy, glossary, append(slices.Clone(parents), service))
+	} else if sc.Type == kDeepL {
+		trans, err = getTranslatorDeepL(sc, proxy, glossary)
+	} else if sc.Type == kAnthropic {
+		trans, err = getTranslatorAnthropic(sc, proxy, glossary)
+	} else if sc.Type == kGemini {
+		trans, err = getTranslatorGemini(sc, proxy, glossary)
+	} else if sc.Type == kOllama {
+		trans, err = getTranslatorOllama(sc, proxy, glossary)
+	} else if sc.Type == kLibre {
+		trans, err = getTranslatorLibre(sc, proxy, glossary)
+	} else if sc.Name == kOpenAI || sc.Type == kOpenAI || sc.Type == kAzure {
 		trans, err = getTranslatorOpenAI(sc, proxy, glossary)
 	}
 	return trans, err
 }
 
-func getTranslatorOpenAI(sc *config.ServiceConfig, proxy string, glossary map[string]string) (Translator, error) {
+func getTranslatorRouter(sc *config.ServiceConfig, proxy string, glossary util.Glossary, parents []string) (Translator, error) {
+	routes := sc.GetRoutes()
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("error creating %s: no routes", sc.Name)
+	}
+
+	// 多条规则共用同一个服务时只构造一次
+	built := make(map[string]Translator)
+	closeBuilt := func() {
+		for _, trans := range built {
+			if c, ok := trans.(io.Closer); ok {
+				c.Close()
+			}
+		}
+	}
+	rs := make([]router.Route, 0, len(routes))
+	for _, r := range routes {
+		if r.Service == "" {
+			closeBuilt()
+			return nil, fmt.Errorf("error creating %s: route without service", sc.Name)
+		}
+		trans, ok := built[r.Service]
+		if !ok {
+			var err error
+			if trans, err = getTranslator(r.Service, proxy, glossary, parents); err != nil {
+				closeBuilt()
+				return nil, fmt.Errorf("error creating %s: route service %s: %w", sc.Name, r.Service, err)
+			}
+			built[r.Service] = trans
+		}
+		rs = append(rs, router.Route{To: r.To, From: r.From, Name: r.Service, Translator: trans})
+	}
+
+	return router.New(rs)
+}
+
+func getTranslatorOpenAI(sc *config.ServiceConfig, proxy string, glossary util.Glossary) (Translator, error) {
 	if err := sc.ValidateEnvArgs(); err != nil {
 		return nil, err
 	}
 
 	return openai.New(sc, openai.WithProxy(proxy), openai.WithGlossary(glossary))
 }
+
+func getTranslatorDeepL(sc *config.ServiceConfig, proxy string, glossary util.Glossary) (Translator, error) {
+	if err := sc.ValidateEnvArgs(); err != nil {
+		return nil, err
+	}
+
+	return deepl.New(sc, deepl.WithProxy(proxy), deepl.WithGlossary(glossary))
+}
+
+func getTranslatorAnthropic(sc *config.ServiceConfig, proxy string, glossary util.Glossary) (Translator, error) {
+	if err := sc.ValidateEnvArgs(); err != nil {
+		return nil, err
+	}
+
+	return anthropic.New(sc, anthropic.WithProxy(proxy), anthropic.WithGlossary(glossary))
+}
+
+func getTranslatorGemini(sc *config.ServiceConfig, proxy string, glossary util.Glossary) (Translator, error) {
+	if err := sc.ValidateEnvArgs(); err != nil {
+		return nil, err
+	}
+
+	return gemini.New(sc, gemini.WithProxy(proxy), gemini.WithGlossary(glossary))
+}
+
+func getTranslatorOllama(sc *config.ServiceConfig, proxy string, glossary util.Glossary) (Translator, error) {
+	if err := sc.ValidateEnvArgs(); err != nil {
+		return nil, err
+	}
+
+	return ollama.New(sc, ollama.WithProxy(proxy), ollama.WithGlossary(glossary))
+}
+
+func getTranslatorLibre(sc *config.ServiceConfig, proxy string, glossary util.Glossary) (Translator, error) {
+	if err := sc.ValidateEnvArgs(); err != nil {
+		return nil, err
+	}
+
+	return libretranslate.New(sc, libretranslate.WithProxy(proxy), libretranslate.WithGlossary(glossary))
+}
+
+func getTranslatorFallback(sc *config.ServiceConfig, proxy string, glossary util.Glossary, parents []string) (Translator, error) {
+	var names []string
+	var translators []fallback.Translator
+	for _, name := range sc.GetServices() {
+		trans, err := getTranslator(name, proxy, glossary, parents)
+		if err != nil { // 跳过未配置好的服务，只要还有可用的服务
+			log.Printf("Warning: skip service %s in %s: %v", name, sc.Name, err)
+			continue
+		}
+		names = append(names, name)
+		translators = append(translators, trans)
+	}
+	if len(translators) == 0 {
+		return nil, fmt.Errorf("error creating %s: no available services in %v", sc.Name, sc.GetServices())
+	}
+
+	return fallback.New(names, translators)
+}
@@ -0,0 +1,404 @@
+// DeepL API文档：
+// https://developers.deepl.com/docs/api-reference/translate
+
+package deepl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/middleware"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+	"github.com/smilingpoplar/translate/util"
+)
+
+const (
+	FreeBaseURL = "https://api-free.deepl.com"
+	ProBaseURL  = "https://api.deepl.com"
+
+	// 免费版的key以:fx结尾
+	freeKeySuffix = ":fx"
+	// 单次请求最多50条文本
+	maxTextsPerRequest = 50
+	// 超出额度
+	statusQuotaExceeded = 456
+)
+
+type DeepL struct {
+	client      *http.Client
+	handler     middleware.Handler
+	glossary    util.Glossary
+	onTrans     func([]string) error
+	Name        string
+	apiKey      string
+	baseURL     string
+	sourceLang  string
+	formality   string
+	tagHandling string
+	cache       *util.Cache
+
+	mu         sync.Mutex
+	glossaries map[string]*nativeGlossary // 源语言>目标语言 => DeepL术语表
+}
+
+// nativeGlossary 一个语言对的DeepL术语表，创建时只阻塞同一语言对的请求
+type nativeGlossary struct {
+	mu   sync.Mutex
+	id   string // ""表示不支持原生术语表
+	done bool   // 已创建，或确定不能创建
+}
+
+type option func(*DeepL) error
+
+func New(sc *config.ServiceConfig, opts ...option) (*DeepL, error) {
+	service := sc.Name
+	key := sc.GetEnvValue("api-key")
+	baseURL := sc.GetEnvValue("base-url")
+	if baseURL == "" {
+		baseURL = ProBaseURL
+		if strings.HasSuffix(key, freeKeySuffix) {
+			baseURL = FreeBaseURL
+		}
+	}
+
+	d := &DeepL{
+		client:      &http.Client{},
+		Name:        service,
+		apiKey:      key,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		sourceLang:  sc.GetEnvValue("source-lang"),
+		formality:   sc.GetEnvValue("formality"),
+		tagHandling: sc.GetEnvValue("tag-handling"),
+		glossaries:  make(map[string]*nativeGlossary),
+	}
+	for _, opt := range opts {
+		if err := opt(d); err != nil {
+			return nil, fmt.Errorf("error creating deepl translator: %w", err)
+		}
+	}
+	if cache, err := util.NewCache(service, 20*time.Minute); err == nil {
+		d.cache = cache
+	} else {
+		return nil, fmt.Errorf("error creating deepl translator: %w", err)
+	}
+
+	chain := middleware.Chain(
+		middleware.TextsLimit(50000),
+		middleware.OnTranslated(&d.onTrans),
+		middleware.PassThrough(),
+		d.withGlossary,
+		middleware.TextsCountLimit(maxTextsPerRequest),
+		middleware.Retry(5, 3),
+		middleware.Cache(d.cache),
+		middleware.RateLimit(sc.GetRpm()),
+		middleware.Concurrent(sc.GetMaxConcurrency()),
+	)
+	d.handler = chain(d.translate)
+
+	return d, nil
+}
+
+func WithProxy(proxy string) option {
+	return func(d *DeepL) error {
+		return util.SetProxy(proxy, d.client)
+	}
+}
+
+func WithGlossary(glossary util.Glossary) option {
+	return func(d *DeepL) error {
+		d.glossary = glossary
+		return nil
+	}
+}
+
+// withGlossary 优先使用DeepL原生术语表，不支持时退回占位符方式
+func (d *DeepL) withGlossary(handler middleware.Handler) middleware.Handler {
+	placeholder := middleware.Glossary(d.glossary)(handler)
+	return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		if d.nativeGlossaryID(ctx, fromLang, toLang) != "" {
+			return handler(ctx, texts, fromLang, toLang)
+		}
+		return placeholder(ctx, texts, fromLang, toLang)
+	}
+}
+
+type translateRequest struct {
+	Text        []string `json:"text"`
+	TargetLang  string   `json:"target_lang"`
+	SourceLang  string   `json:"source_lang,omitempty"`
+	Formality   string   `json:"formality,omitempty"`
+	TagHandling string   `json:"tag_handling,omitempty"`
+	GlossaryID  string   `json:"glossary_id,omitempty"`
+}
+
+type translation struct {
+	DetectedSourceLanguage string `json:"detected_source_language"`
+	Text                   string `json:"text"`
+}
+
+type translateResponse struct {
+	Translations []translation `json:"translations"`
+}
+
+func (d *DeepL) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	reqBody := translateRequest{
+		Text:        texts,
+		TargetLang:  targetLang(toLang),
+		SourceLang:  sourceLang(d.source(fromLang)),
+		Formality:   d.formality,
+		TagHandling: d.tagHandling,
+		GlossaryID:  d.nativeGlossaryID(ctx, fromLang, toLang),
+	}
+
+	var data translateResponse
+	if err := d.doJSON(ctx, "POST", "/v2/translate", reqBody, &data); err != nil {
+		return nil, err
+	}
+	if len(data.Translations) != len(texts) {
+		return nil, fmt.Errorf("error translating: %w, expected %d, got %d",
+			transerrors.ErrCountMismatch, len(texts), len(data.Translations))
+	}
+
+	result := make([]string, len(data.Translations))
+	for i, t := range data.Translations {
+		result[i] = t.Text
+	}
+	return result, nil
+}
+
+// source 参数指定的源语言优先于配置的source-lang
+func (d *DeepL) source(fromLang string) string {
+	if fromLang != "" {
+		return fromLang
+	}
+	return d.sourceLang
+}
+
+// nativeGlossaryID 返回语言对对应的DeepL术语表id，首次使用时创建。
+// 原生术语表要求指定源语言，未指定或语言对不支持时返回""
+func (d *DeepL) nativeGlossaryID(ctx context.Context, fromLang, toLang string) string {
+	fromLang = d.source(fromLang)
+	if len(d.glossary) == 0 || fromLang == "" {
+		return ""
+	}
+
+	key := fromLang + ">" + toLang
+	d.mu.Lock()
+	g, ok := d.glossaries[key]
+	if !ok {
+		g = &nativeGlossary{}
+		d.glossaries[key] = g
+	}
+	d.mu.Unlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done {
+		return g.id
+	}
+	id, err := d.createGlossary(ctx, fromLang, toLang)
+	if err != nil {
+		log.Printf("Warning: deepl native glossary unavailable for %s-%s, fallback to placeholders: %v", fromLang, toLang, err)
+		if !isPermanent(err) { // 网络错误、限流、服务端错误等下次再试
+			return ""
+		}
+	}
+	g.id, g.done = id, true
+	return id
+}
+
+// errUnsupportedGlossary 术语表不能转为DeepL原生术语表
+var errUnsupportedGlossary = errors.New("unsupported by deepl glossary")
+
+// apiError DeepL接口返回的错误状态码
+type apiError struct {
+	status int
+	body   string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("API error: %d - %s", e.status, e.body)
+}
+
+// isPermanent 重试也不会成功的错误：术语表不适用，或请求被拒绝（如不支持的语言对）
+func isPermanent(err error) bool {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.status >= 400 && apiErr.status < 500
+	}
+	return errors.Is(err, errUnsupportedGlossary)
+}
+
+type glossaryRequest struct {
+	Name          string `json:"name"`
+	SourceLang    string `json:"source_lang"`
+	TargetLang    string `json:"target_lang"`
+	Entries       string `json:"entries"`
+	EntriesFormat string `json:"entries_format"`
+}
+
+type glossaryResponse struct {
+	GlossaryID string `json:"glossary_id"`
+}
+
+func (d *DeepL) createGlossary(ctx context.Context, fromLang, toLang string) (string, error) {
+	for from, entry := range d.glossary {
+		if entry.Regex || entry.Inflect || entry.Substring {
+			return "", fmt.Errorf("%w: term %q uses an unsupported match mode", errUnsupportedGlossary, from)
+		}
+	}
+
+	var entries []string
+	for from, to := range d.glossary.Terms(toLang) {
+		if to == "" { // 没有译文的术语映射为自身，保持原文
+			to = from
+		}
+		// DeepL术语表不能包含制表符或换行
+		if strings.ContainsAny(from+to, "\t\r\n") {
+			continue
+		}
+		entries = append(entries, from+"\t"+to)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("%w: no valid glossary entries", errUnsupportedGlossary)
+	}
+
+	reqBody := glossaryRequest{
+		Name:          fmt.Sprintf("translate-%s-%s", fromLang, toLang),
+		SourceLang:    glossaryLang(fromLang),
+		TargetLang:    glossaryLang(toLang),
+		Entries:       strings.Join(entries, "\n"),
+		EntriesFormat: "tsv",
+	}
+	var data glossaryResponse
+	if err := d.doJSON(ctx, "POST", "/v2/glossaries", reqBody, &data); err != nil {
+		return "", err
+	}
+	return data.GlossaryID, nil
+}
+
+func (d *DeepL) deleteGlossary(ctx context.Context, id string) error {
+	return d.doJSON(ctx, "DELETE", "/v2/glossaries/"+id, nil, nil)
+}
+
+func (d *DeepL) doJSON(ctx context.Context, method, path string, reqBody, respData any) error {
+	var body io.Reader
+	if reqBody != nil {
+		reqJSON, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("error marshaling request: %w", err)
+		}
+		body = bytes.NewReader(reqJSON)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, d.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+d.apiKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return transerrors.ErrTooManyRequests
+	case resp.StatusCode == statusQuotaExceeded:
+		return fmt.Errorf("deepl quota exceeded: %s", string(respBody))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return &apiError{status: resp.StatusCode, body: string(respBody)}
+	}
+
+	if respData == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, respData); err != nil {
+		return fmt.Errorf("error unmarshalling JSON: %w, resp body: %s", err, string(respBody))
+	}
+	return nil
+}
+
+// targetLang 将zh-CN等语言代码转为DeepL的目标语言代码
+func targetLang(lang string) string {
+	switch strings.ToLower(lang) {
+	case "zh", "zh-cn", "zh-hans", "zh-sg":
+		return "ZH-HANS"
+	case "zh-tw", "zh-hant", "zh-hk":
+		return "ZH-HANT"
+	case "en":
+		return "EN-US"
+	case "pt":
+		return "PT-PT"
+	}
+	return strings.ToUpper(lang)
+}
+
+// sourceLang 源语言只接受不带地区的代码
+func sourceLang(lang string) string {
+	return strings.ToUpper(glossaryLang(lang))
+}
+
+// glossaryLang 术语表只接受不带地区的小写代码
+func glossaryLang(lang string) string {
+	base, _, _ := strings.Cut(lang, "-")
+	return strings.ToLower(base)
+}
+
+func (d *DeepL) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return d.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (d *DeepL) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	return d.handler(ctx, texts, fromLang, toLang)
+}
+
+func (d *DeepL) OnTranslated(f func([]string) error) {
+	d.onTrans = f
+}
+
+// Close 删除本次创建的DeepL术语表，并关闭缓存
+func (d *DeepL) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	d.mu.Lock()
+	glossaries := make([]*nativeGlossary, 0, len(d.glossaries))
+	for _, g := range d.glossaries {
+		glossaries = append(glossaries, g)
+	}
+	d.mu.Unlock()
+	for _, g := range glossaries {
+		g.mu.Lock()
+		if g.id != "" {
+			if err := d.deleteGlossary(ctx, g.id); err != nil {
+				log.Printf("Warning: failed to delete deepl glossary %s: %v", g.id, err)
+			}
+		}
+		g.mu.Unlock()
+	}
+
+	return d.cache.Close()
+}
@@ -0,0 +1,184 @@
+package deepl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/util"
+)
+
+// newTestDeepL 创建指向stand-in服务的DeepL翻译器
+func newTestDeepL(t *testing.T, handler http.HandlerFunc, env map[string]string, glossary map[string]string) *DeepL {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv("TMPDIR", t.TempDir()) // 隔离缓存文件
+	t.Setenv("DEEPL_API_KEY", "test-key:fx")
+	t.Setenv("DEEPL_BASE_URL", server.URL)
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	d, err := New(config.NewServiceConfig("deepl"), WithGlossary(util.NewGlossary(glossary)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func writeTranslations(w http.ResponseWriter, texts []string) {
+	var resp translateResponse
+	for _, text := range texts {
+		resp.Translations = append(resp.Translations, translation{"EN", strings.ToUpper(text)})
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestTranslate(t *testing.T) {
+	d := newTestDeepL(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "DeepL-Auth-Key test-key:fx" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		var req translateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.TargetLang != "DE" || req.Formality != "more" || req.TagHandling != "html" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		writeTranslations(w, req.Text)
+	}, map[string]string{"DEEPL_FORMALITY": "more", "DEEPL_TAG_HANDLING": "html"}, nil)
+
+	got, err := d.Translate([]string{"hello", "world"}, "", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "HELLO" || got[1] != "WORLD" {
+		t.Errorf("unexpected result %q", got)
+	}
+}
+
+func TestTranslate_NativeGlossary(t *testing.T) {
+	var created atomic.Int32
+	d := newTestDeepL(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v2/glossaries":
+			created.Add(1)
+			var req glossaryRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.SourceLang != "en" || req.TargetLang != "de" || req.Entries != "AWS\tAmazon Web Services" {
+				t.Errorf("unexpected glossary request %+v", req)
+			}
+			json.NewEncoder(w).Encode(glossaryResponse{GlossaryID: "g1"})
+		case r.Method == "POST" && r.URL.Path == "/v2/translate":
+			var req translateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.GlossaryID != "g1" || req.SourceLang != "EN" {
+				t.Errorf("unexpected request %+v", req)
+			}
+			// 原生术语表模式下原文不应被替换为占位符
+			if strings.Contains(req.Text[0], "{ID_") {
+				t.Errorf("unexpected placeholder in %q", req.Text[0])
+			}
+			writeTranslations(w, req.Text)
+		case r.Method == "DELETE":
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}, map[string]string{"DEEPL_SOURCE_LANG": "en"}, map[string]string{"AWS": "Amazon Web Services"})
+
+	for range 2 {
+		if _, err := d.Translate([]string{"use AWS"}, "", "de"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if created.Load() != 1 {
+		t.Errorf("expected glossary created once, got %d", created.Load())
+	}
+}
+
+func TestTranslate_NativeGlossaryRetryAfterTransientError(t *testing.T) {
+	for _, tt := range []struct {
+		status  int
+		created int32 // 第二次翻译时创建术语表的请求数
+	}{
+		{http.StatusServiceUnavailable, 2}, // 暂时的错误，下次再试
+		{http.StatusBadRequest, 1},         // 不支持的语言对，不再创建
+	} {
+		var created atomic.Int32
+		d := newTestDeepL(t, func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "POST" && r.URL.Path == "/v2/glossaries":
+				if created.Add(1) == 1 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				json.NewEncoder(w).Encode(glossaryResponse{GlossaryID: "g1"})
+			case r.Method == "POST" && r.URL.Path == "/v2/translate":
+				var req translateRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				writeTranslations(w, req.Text)
+			case r.Method == "DELETE":
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+		}, map[string]string{"DEEPL_SOURCE_LANG": "en"}, map[string]string{"AWS": "Amazon Web Services"})
+
+		for _, text := range []string{"use AWS", "run AWS"} {
+			if _, err := d.Translate([]string{text}, "", "de"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if created.Load() != tt.created {
+			t.Errorf("status %d: expected %d glossary requests, got %d", tt.status, tt.created, created.Load())
+		}
+	}
+}
+
+func TestTranslate_PlaceholderGlossaryWithoutSourceLang(t *testing.T) {
+	d := newTestDeepL(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/translate" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req translateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		// 返回原文，保持占位符不变
+		json.NewEncoder(w).Encode(map[string]any{
+			"translations": []map[string]string{{"text": req.Text[0]}},
+		})
+	}, nil, map[string]string{"AWS": "Amazon Web Services"})
+
+	got, err := d.Translate([]string{"use AWS"}, "", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != "use Amazon Web Services" {
+		t.Errorf("unexpected result %q", got[0])
+	}
+}
+
+func TestTargetLang(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"zh-CN": "ZH-HANS",
+		"zh-TW": "ZH-HANT",
+		"en":    "EN-US",
+		"en-GB": "EN-GB",
+		"de":    "DE",
+	}
+	for lang, want := range tests {
+		if got := targetLang(lang); got != want {
+			t.Errorf("targetLang(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package translator
+
+import (
+	"context"
+
+	"github.com/smilingpoplar/translate/util"
+)
+
+// Detection 检测到的语言和置信度(0~1)，无法判断时Lang为""
+type Detection = util.Detection
+
+// Detector 能检测文本语言的服务
+type Detector interface {
+	Detect(ctx context.Context, texts []string) ([]Detection, error)
+}
+
+// Detect 离线检测每条文本的语言
+func Detect(texts []string) []Detection {
+	return util.DetectLangs(texts)
+}
+
+// DetectContext 服务支持语言检测时用服务的结果，否则离线检测
+func DetectContext(ctx context.Context, trans Translator, texts []string) ([]Detection, error) {
+	if d, ok := trans.(Detector); ok {
+		return d.Detect(ctx, texts)
+	}
+	return Detect(texts), nil
+}
@@ -0,0 +1,81 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/smilingpoplar/translate/translator/middleware"
+)
+
+// 与LLM服务的分组大小一致，出错时只需换服务重译出错的这一组
+const batchSize = 2000
+
+type Translator interface {
+	TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error)
+}
+
+// Fallback 按顺序尝试多个服务，某组文本在一个服务上出错（不可重试或重试耗尽）时换下一个服务
+type Fallback struct {
+	names       []string
+	translators []Translator
+	handler     middleware.Handler
+	onTrans     func([]string) error
+}
+
+func New(names []string, translators []Translator) (*Fallback, error) {
+	if len(translators) == 0 || len(names) != len(translators) {
+		return nil, fmt.Errorf("error creating fallback translator: no services")
+	}
+
+	f := &Fallback{names: names, translators: translators}
+	chain := middleware.Chain(
+		middleware.TextsLimit(batchSize),
+		middleware.OnTranslated(&f.onTrans),
+	)
+	f.handler = chain(f.translate)
+	return f, nil
+}
+
+func (f *Fallback) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	var errs []error
+	for i, trans := range f.translators {
+		result, err := trans.TranslateContext(ctx, texts, fromLang, toLang)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil { // 调用方已取消，不再尝试其他服务
+			return nil, ctx.Err()
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", f.names[i], err))
+		if i < len(f.translators)-1 {
+			log.Printf("Warning: %s failed, fallback to %s: %v", f.names[i], f.names[i+1], err)
+		}
+	}
+	return nil, fmt.Errorf("error translating: all services failed: %w", errors.Join(errs...))
+}
+
+func (f *Fallback) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return f.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (f *Fallback) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	return f.handler(ctx, texts, fromLang, toLang)
+}
+
+func (f *Fallback) OnTranslated(fn func([]string) error) {
+	f.onTrans = fn
+}
+
+func (f *Fallback) Close() error {
+	var errs []error
+	for _, trans := range f.translators {
+		if c, ok := trans.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
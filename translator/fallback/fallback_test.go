@@ -0,0 +1,97 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeTranslator 分组中有包含failOn的文本时返回错误，否则返回带前缀的原文
+type fakeTranslator struct {
+	prefix string
+	failOn string
+	calls  int
+}
+
+func (f *fakeTranslator) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	f.calls++
+	result := make([]string, len(texts))
+	for i, text := range texts {
+		if f.failOn != "" && strings.Contains(text, f.failOn) {
+			return nil, errors.New(f.prefix + " failed")
+		}
+		result[i] = f.prefix + text
+	}
+	return result, nil
+}
+
+type failingTranslator struct{ calls int }
+
+func (f *failingTranslator) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	f.calls++
+	return nil, errors.New("outage")
+}
+
+func TestFallback_NextServiceOnError(t *testing.T) {
+	t.Parallel()
+
+	primary := &failingTranslator{}
+	secondary := &fakeTranslator{prefix: "b:"}
+	f, err := New([]string{"a", "b"}, []Translator{primary, secondary})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.Translate([]string{"hello", "world"}, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != "b:hello" || got[1] != "b:world" {
+		t.Errorf("unexpected result %q", got)
+	}
+	if primary.calls != 1 || secondary.calls != 1 {
+		t.Errorf("unexpected calls %d, %d", primary.calls, secondary.calls)
+	}
+}
+
+func TestFallback_MixedBatches(t *testing.T) {
+	t.Parallel()
+
+	// 两段文本超出一组的大小，分成两组
+	long := strings.Repeat("x", batchSize-2)
+	first := &fakeTranslator{prefix: "a:", failOn: "fail"}
+	second := &fakeTranslator{prefix: "b:"}
+	f, err := New([]string{"a", "b"}, []Translator{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.Translate([]string{long, "fail"}, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一组由a翻译，第二组在a上失败后由b翻译
+	if got[0] != "a:"+long || got[1] != "b:fail" {
+		t.Errorf("unexpected result %.10q..., %q", got[0], got[1])
+	}
+}
+
+func TestFallback_StopOnCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	secondary := &fakeTranslator{prefix: "b:"}
+	f, err := New([]string{"a", "b"}, []Translator{&failingTranslator{}, secondary})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.TranslateContext(ctx, []string{"hello"}, "", "zh-CN"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("expected no fallback after cancel, got %d calls", secondary.calls)
+	}
+}
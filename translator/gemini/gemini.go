@@ -0,0 +1,279 @@
+// Gemini generateContent接口文档：
+// https://ai.google.dev/api/generate-content
+
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/llm"
+	"github.com/smilingpoplar/translate/translator/middleware"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+	"github.com/smilingpoplar/translate/util"
+)
+
+const (
+	BaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+	// 单次请求的超时时间，LLM生成较慢
+	requestTimeout = 3 * time.Minute
+)
+
+// responseSchema 约束输出为config.Translation数组
+var responseSchema = map[string]any{
+	"type": "ARRAY",
+	"items": map[string]any{
+		"type": "OBJECT",
+		"properties": map[string]any{
+			"id":   map[string]any{"type": "INTEGER"},
+			"text": map[string]any{"type": "STRING"},
+		},
+		"required":         []string{"id", "text"},
+		"propertyOrdering": []string{"id", "text"},
+	},
+}
+
+type Gemini struct {
+	client    *http.Client
+	model     string
+	system    string
+	handler   middleware.Handler
+	glossary  util.Glossary
+	onTrans   func([]string) error
+	Name      string
+	apiKey    string
+	baseURL   string
+	extraBody map[string]any
+	cache     *util.Cache
+}
+
+type option func(*Gemini) error
+
+func New(sc *config.ServiceConfig, opts ...option) (*Gemini, error) {
+	service := sc.Name
+	baseURL := sc.GetEnvValue("base-url")
+	if baseURL == "" {
+		baseURL = BaseURL
+	}
+
+	g := &Gemini{
+		client:    &http.Client{},
+		model:     strings.TrimPrefix(sc.GetEnvValue("model"), "models/"),
+		system:    sc.GetEnvValue("system-prompt"),
+		Name:      service,
+		apiKey:    sc.GetEnvValue("api-key"),
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		extraBody: sc.GetExtraBody(),
+	}
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return nil, fmt.Errorf("error creating gemini translator: %w", err)
+		}
+	}
+	if cache, err := util.NewCache(service, 20*time.Minute); err == nil {
+		g.cache = cache
+	} else {
+		return nil, fmt.Errorf("error creating gemini translator: %w", err)
+	}
+
+	chain := middleware.Chain(
+		middleware.TextsLimit(2000),
+		middleware.OnTranslated(&g.onTrans),
+		middleware.PassThrough(),
+		middleware.Glossary(g.glossary),
+		middleware.Retry(8, 3),
+		middleware.Cache(g.cache),
+		middleware.RateLimit(sc.GetRpm()),
+		middleware.Concurrent(sc.GetMaxConcurrency()),
+	)
+	g.handler = chain(g.translate)
+
+	return g, nil
+}
+
+func WithProxy(proxy string) option {
+	return func(g *Gemini) error {
+		return util.SetProxy(proxy, g.client)
+	}
+}
+
+func WithGlossary(glossary util.Glossary) option {
+	return func(g *Gemini) error {
+		g.glossary = glossary
+		return nil
+	}
+}
+
+func (g *Gemini) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	prompt, err := config.GetPrompt(texts, fromLang, toLang)
+	if err != nil {
+		return nil, fmt.Errorf("error translating: %w", err)
+	}
+
+	result, err := g.sendRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := llm.ParseTranslations(result, texts)
+	if err != nil {
+		return nil, fmt.Errorf("error translating: %w", err)
+	}
+	return parsed, nil
+}
+
+func (g *Gemini) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return g.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (g *Gemini) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	return g.handler(ctx, texts, fromLang, toLang)
+}
+
+func (g *Gemini) OnTranslated(f func([]string) error) {
+	g.onTrans = f
+}
+
+func (g *Gemini) Close() error {
+	return g.cache.Close()
+}
+
+type part struct {
+	Text    string `json:"text"`
+	Thought bool   `json:"thought,omitempty"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type generationConfig struct {
+	ResponseMimeType string         `json:"responseMimeType"`
+	ResponseSchema   map[string]any `json:"responseSchema"`
+}
+
+type generateRequest struct {
+	SystemInstruction *content         `json:"systemInstruction,omitempty"`
+	Contents          []content        `json:"contents"`
+	GenerationConfig  generationConfig `json:"generationConfig"`
+}
+
+type candidate struct {
+	Content      content `json:"content"`
+	FinishReason string  `json:"finishReason"`
+}
+
+type generateResponse struct {
+	Candidates     []candidate `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+func (g *Gemini) sendRequest(ctx context.Context, prompt string) (string, error) {
+	request := generateRequest{
+		Contents: []content{{Role: "user", Parts: []part{{Text: prompt}}}},
+		GenerationConfig: generationConfig{
+			ResponseMimeType: "application/json",
+			ResponseSchema:   responseSchema,
+		},
+	}
+	if g.system != "" {
+		request.SystemInstruction = &content{Parts: []part{{Text: g.system}}}
+	}
+	reqJSON, err := util.MergeJSON(request, g.extraBody)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	apiURL := fmt.Sprintf("%s/models/%s:generateContent", g.baseURL, g.model)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(reqJSON))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.apiKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp.StatusCode, body)
+	}
+
+	var response generateResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error unmarshalling JSON: %w, resp body: %s", err, string(body))
+	}
+	return responseText(&response)
+}
+
+// statusError 将http错误映射为transerrors，其余附带接口返回的错误信息
+func statusError(statusCode int, body []byte) error {
+	// 限流，或模型过载
+	if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable {
+		return transerrors.ErrTooManyRequests
+	}
+
+	var e errorResponse
+	if err := json.Unmarshal(body, &e); err == nil && e.Error.Message != "" {
+		return fmt.Errorf("API error: %d %s - %s", statusCode, e.Error.Status, e.Error.Message)
+	}
+	return fmt.Errorf("API error: %d - %s", statusCode, string(body))
+}
+
+// responseText 提取首个候选的文本，将拦截、截断等结束原因映射为transerrors
+func responseText(response *generateResponse) (string, error) {
+	if reason := response.PromptFeedback.BlockReason; reason != "" {
+		return "", fmt.Errorf("error making request: %w, prompt block reason: %s", transerrors.ErrContentBlocked, reason)
+	}
+	if len(response.Candidates) == 0 {
+		return "", fmt.Errorf("error making request: %w, no candidates", transerrors.ErrInvalidJSON)
+	}
+
+	c := response.Candidates[0]
+	switch c.FinishReason {
+	case "", "STOP":
+	case "MAX_TOKENS":
+		return "", fmt.Errorf("error making request: %w, finish reason: %s", transerrors.ErrTruncated, c.FinishReason)
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "LANGUAGE":
+		return "", fmt.Errorf("error making request: %w, finish reason: %s", transerrors.ErrContentBlocked, c.FinishReason)
+	default:
+		// MALFORMED_FUNCTION_CALL、OTHER等偶发情况，重试可能恢复
+		return "", fmt.Errorf("error making request: %w, finish reason: %s", transerrors.ErrInvalidJSON, c.FinishReason)
+	}
+
+	var sb strings.Builder
+	for _, p := range c.Content.Parts {
+		if !p.Thought {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String(), nil
+}
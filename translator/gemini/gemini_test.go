@@ -0,0 +1,121 @@
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+)
+
+// newTestGemini 创建指向stand-in服务的Gemini翻译器
+func newTestGemini(t *testing.T, handler http.HandlerFunc) *Gemini {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv("TMPDIR", t.TempDir()) // 隔离缓存文件
+	t.Setenv("GEMINI_BASE_URL", server.URL+"/v1beta")
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	t.Setenv("GEMINI_MODEL", "gemini-test")
+
+	g, err := New(config.NewServiceConfig("gemini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+func TestTranslate(t *testing.T) {
+	g := newTestGemini(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-test:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("unexpected api key %q", r.Header.Get("x-goog-api-key"))
+		}
+		var req generateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.GenerationConfig.ResponseMimeType != "application/json" || req.GenerationConfig.ResponseSchema["type"] != "ARRAY" {
+			t.Errorf("unexpected generation config %+v", req.GenerationConfig)
+		}
+
+		text := `[{"id":0,"text":"你好"},{"id":1,"text":"世界"}]`
+		fmt.Fprintf(w, `{"candidates":[{"content":{"parts":[{"text":"thinking...","thought":true},{"text":%q}]},"finishReason":"STOP"}]}`, text)
+	})
+
+	got, err := g.Translate([]string{"hello", "world"}, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "你好" || got[1] != "世界" {
+		t.Errorf("unexpected result %q", got)
+	}
+}
+
+func TestTranslate_SafetyBlockNotRetried(t *testing.T) {
+	calls := 0
+	g := newTestGemini(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY"}]}`)
+	})
+
+	_, err := g.Translate([]string{"hello"}, "", "zh-CN")
+	if !errors.Is(err, transerrors.ErrContentBlocked) {
+		t.Errorf("expected ErrContentBlocked, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestResponseText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{"prompt blocked", `{"promptFeedback":{"blockReason":"SAFETY"}}`, transerrors.ErrContentBlocked},
+		{"max tokens", `{"candidates":[{"finishReason":"MAX_TOKENS"}]}`, transerrors.ErrTruncated},
+		{"recitation", `{"candidates":[{"finishReason":"RECITATION"}]}`, transerrors.ErrContentBlocked},
+		{"other", `{"candidates":[{"finishReason":"OTHER"}]}`, transerrors.ErrInvalidJSON},
+		{"no candidates", `{}`, transerrors.ErrInvalidJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resp generateResponse
+			if err := json.Unmarshal([]byte(tt.body), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := responseText(&resp); !errors.Is(err, tt.wantErr) {
+				t.Errorf("responseText() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	t.Parallel()
+
+	if err := statusError(http.StatusServiceUnavailable, nil); !errors.Is(err, transerrors.ErrTooManyRequests) {
+		t.Errorf("expected ErrTooManyRequests, got %v", err)
+	}
+	body := []byte(`{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`)
+	want := "API error: 400 INVALID_ARGUMENT - API key not valid"
+	if err := statusError(http.StatusBadRequest, body); err == nil || err.Error() != want {
+		t.Errorf("statusError() = %v, want %q", err, want)
+	}
+}
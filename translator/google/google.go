@@ -13,25 +13,37 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/time/rate"
+
 	"github.com/smilingpoplar/translate/translator/middleware"
 	"github.com/smilingpoplar/translate/translator/transerrors"
 	"github.com/smilingpoplar/translate/util"
 )
 
-const BaseURL = "https://translate.google.com/translate_a/t"
+const (
+	BaseURL   = "https://translate.google.com/translate_a/t"
+	DetectURL = "https://translate.googleapis.com/translate_a/single"
+)
 
 type Google struct {
-	client   *http.Client
-	handler  middleware.Handler
-	glossary map[string]string
-	onTrans  func([]string) error
+	client    *http.Client
+	handler   middleware.Handler
+	limiter   *rate.Limiter // 检测只能逐条请求，单独限速
+	detectURL string
+	glossary  util.Glossary
+	onTrans   func([]string) error
 }
 
+// 检测接口没有公开的限额，每分钟最多请求的次数
+const detectRpm = 300
+
 type option func(*Google) error
 
 func New(opts ...option) (*Google, error) {
 	g := &Google{
-		client: &http.Client{},
+		client:    &http.Client{},
+		limiter:   rate.NewLimiter(rate.Limit(detectRpm)/60, 10),
+		detectURL: DetectURL,
 	}
 	for _, opt := range opts {
 		if err := opt(g); err != nil {
@@ -41,6 +53,7 @@ func New(opts ...option) (*Google, error) {
 	chain := middleware.Chain(
 		middleware.TextsLimit(1000000),
 		middleware.OnTranslated(&g.onTrans),
+		middleware.PassThrough(),
 		middleware.Glossary(g.glossary),
 		middleware.Retry(5, 5),
 	)
@@ -55,17 +68,21 @@ func WithProxy(proxy string) option {
 	}
 }
 
-func WithGlossary(glossary map[string]string) option {
+func WithGlossary(glossary util.Glossary) option {
 	return func(g *Google) error {
 		g.glossary = glossary
 		return nil
 	}
 }
 
-func (g *Google) translate(texts []string, toLang string) ([]string, error) {
+func (g *Google) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 	// 构造请求
+	sl := fromLang
+	if sl == "" {
+		sl = "auto"
+	}
 	queryParams := url.Values{}
-	queryParams.Set("sl", "auto")
+	queryParams.Set("sl", sl)
 	queryParams.Set("tl", toLang)
 	queryParams.Set("ie", "UTF-8")
 	queryParams.Set("oe", "UTF-8")
@@ -79,13 +96,12 @@ func (g *Google) translate(texts []string, toLang string) ([]string, error) {
 	for _, text := range texts {
 		postData.Add("q", text)
 	}
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(postData.Encode()))
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(postData.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-	req = req.WithContext(ctx)
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("User-Agent", userAgent())
@@ -110,24 +126,142 @@ func (g *Google) translate(texts []string, toLang string) ([]string, error) {
 		return nil, fmt.Errorf("error http status: %s", http.StatusText(resp.StatusCode))
 	}
 
-	// 解析响应
-	var data [][]string
-	err = json.Unmarshal(body, &data)
-	if err != nil {
+	return parseResponse(body)
+}
+
+// parseResponse sl=auto时每项为[译文, 检测到的源语言]，指定源语言时每项为译文
+func parseResponse(body []byte) ([]string, error) {
+	var single string
+	if json.Unmarshal(body, &single) == nil { // 指定源语言且只有一条文本
+		return []string{single}, nil
+	}
+
+	var data []json.RawMessage
+	if err := json.Unmarshal(body, &data); err != nil {
 		return nil, fmt.Errorf("error unmarshalling JSON: %w, resp body: %s", err, string(body))
 	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("error resp data: %s", string(body))
+	}
 
-	if len(data) == 0 || len(data[0]) != 2 {
-		return nil, fmt.Errorf("error resp data: %v", data)
+	result := make([]string, 0, len(data))
+	for _, item := range data {
+		var pair []string
+		if json.Unmarshal(item, &pair) == nil && len(pair) == 2 {
+			result = append(result, pair[0])
+			continue
+		}
+		var text string
+		if err := json.Unmarshal(item, &text); err != nil {
+			return nil, fmt.Errorf("error resp data: %s", string(body))
+		}
+		result = append(result, text)
 	}
+	return result, nil
+}
+
+// 检测时每条文本最多发送的字符数
+const detectMaxRunes = 500
 
-	var result []string
-	for _, line := range data {
-		result = append(result, line[0])
+type detectResponse struct {
+	Src        string  `json:"src"`
+	Confidence float64 `json:"confidence"`
+	LDResult   struct {
+		SrcLangs            []string  `json:"srclangs"`
+		SrcLangsConfidences []float64 `json:"srclangs_confidences"`
+	} `json:"ld_result"`
+}
+
+// Detect 用Google的语言检测结果，空白文本不检测，相同的文本只检测一次。
+// 检测接口的ld_result针对整个输入，不能把多条文本合并成一个请求，只能逐条请求，限速并和翻译一样重试
+func (g *Google) Detect(ctx context.Context, texts []string) ([]util.Detection, error) {
+	result := make([]util.Detection, len(texts))
+	detected := make(map[string]util.Detection)
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if rs := []rune(text); len(rs) > detectMaxRunes {
+			text = string(rs[:detectMaxRunes])
+		}
+		d, ok := detected[text]
+		if !ok {
+			err := middleware.RetryCall(ctx, 5, 5, func() error {
+				if err := g.limiter.Wait(ctx); err != nil {
+					return err
+				}
+				var err error
+				d, err = g.detect(ctx, text)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			detected[text] = d
+		}
+		result[i] = d
 	}
 	return result, nil
 }
 
+// detect 请求检测一条文本的语言
+func (g *Google) detect(ctx context.Context, text string) (util.Detection, error) {
+	queryParams := url.Values{}
+	queryParams.Set("client", "gtx")
+	queryParams.Set("sl", "auto")
+	queryParams.Set("tl", "en")
+	queryParams.Set("dt", "t")
+	queryParams.Set("dj", "1")
+	queryParams.Set("q", text)
+	apiURL := fmt.Sprintf("%s?%s", g.detectURL, queryParams.Encode())
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return util.Detection{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent())
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return util.Detection{}, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return util.Detection{}, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return util.Detection{}, transerrors.ErrTooManyRequests
+		}
+		return util.Detection{}, fmt.Errorf("error http status: %s", http.StatusText(resp.StatusCode))
+	}
+	return parseDetection(body)
+}
+
+// parseDetection 优先取ld_result中置信度最高的语言
+func parseDetection(body []byte) (util.Detection, error) {
+	var data detectResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return util.Detection{}, fmt.Errorf("error unmarshalling JSON: %w, resp body: %s", err, string(body))
+	}
+
+	d := util.Detection{Lang: data.Src, Confidence: data.Confidence}
+	if ld := data.LDResult; len(ld.SrcLangs) > 0 {
+		d.Lang = ld.SrcLangs[0]
+		if len(ld.SrcLangsConfidences) > 0 {
+			d.Confidence = ld.SrcLangsConfidences[0]
+		}
+	}
+	if d.Lang == "" {
+		return util.Detection{}, fmt.Errorf("error resp data: %s", string(body))
+	}
+	return d, nil
+}
+
 func userAgent() string {
 	return fmt.Sprintf("GoogleTranslate/6.%d.0.06.%d (Linux; U; Android %d; %s)",
 		util.RandInt(10, 100),
@@ -149,8 +283,12 @@ func randModelNum(letterCount, digitCount int) string {
 	return string(data)
 }
 
-func (g *Google) Translate(texts []string, toLang string) ([]string, error) {
-	return g.handler(texts, toLang)
+func (g *Google) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return g.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (g *Google) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	return g.handler(ctx, texts, fromLang, toLang)
 }
 
 func (g *Google) OnTranslated(f func([]string) error) {
@@ -1,6 +1,12 @@
 package google
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/smilingpoplar/translate/translator/middleware"
@@ -14,7 +20,7 @@ func TestTranslate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	got, err := g.Translate(texts, "zh-CN")
+	got, err := g.Translate(texts, "", "zh-CN")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,7 +45,7 @@ func TestTextsLimit(t *testing.T) {
 	mw := middleware.TextsLimit(6)
 	g.handler = mw(g.translate)
 
-	got, err := g.Translate(texts, "zh-CN")
+	got, err := g.Translate(texts, "", "zh-CN")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -64,7 +70,7 @@ func TestTextsLimitWithLongText(t *testing.T) {
 	mw := middleware.TextsLimit(8)
 	g.handler = mw(g.translate)
 
-	got, err := g.Translate(texts, "zh-CN")
+	got, err := g.Translate(texts, "", "zh-CN")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -77,3 +83,80 @@ func TestTextsLimitWithLongText(t *testing.T) {
 		}
 	}
 }
+
+func TestParseResponse(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`[["你好","en"],["世界","en"]]`, []string{"你好", "世界"}},
+		{`["你好","世界"]`, []string{"你好", "世界"}},
+		{`"你好"`, []string{"你好"}},
+	}
+	for _, tt := range tests {
+		got, err := parseResponse([]byte(tt.body))
+		if err != nil {
+			t.Fatalf("parseResponse(%s): %v", tt.body, err)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("parseResponse(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+	if _, err := parseResponse([]byte(`[]`)); err == nil {
+		t.Error("expect error for empty response")
+	}
+}
+
+func TestParseDetection(t *testing.T) {
+	t.Parallel()
+	body := `{"sentences":[{"trans":"Hello","orig":"Bonjour"}],"src":"fr","confidence":0.8,` +
+		`"ld_result":{"srclangs":["fr"],"srclangs_confidences":[0.95],"extended_srclangs":["fr"]}}`
+	got, err := parseDetection([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Lang != "fr" || got.Confidence != 0.95 {
+		t.Errorf("parseDetection() = %+v, want fr 0.95", got)
+	}
+
+	got, err = parseDetection([]byte(`{"src":"de","confidence":0.7}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Lang != "de" || got.Confidence != 0.7 {
+		t.Errorf("parseDetection() = %+v, want de 0.7", got)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		lang := map[string]string{"Bonjour": "fr", "Hallo": "de"}[r.URL.Query().Get("q")]
+		fmt.Fprintf(w, `{"src":%q,"confidence":0.9}`, lang)
+	}))
+	defer server.Close()
+
+	g, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.detectURL = server.URL
+
+	got, err := g.Detect(context.Background(), []string{"Bonjour", "", "Hallo", "Bonjour"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"fr", "", "de", "fr"}
+	for i, d := range got {
+		if d.Lang != want[i] {
+			t.Errorf("Detect()[%d] = %+v, want %s", i, d, want[i])
+		}
+	}
+	// 空白文本不检测，相同的文本只请求一次
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expect 2 requests, got %d", n)
+	}
+}
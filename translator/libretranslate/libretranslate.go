@@ -0,0 +1,284 @@
+// LibreTranslate接口文档：
+// https://docs.libretranslate.com/guides/api_usage/
+
+package libretranslate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/middleware"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+	"github.com/smilingpoplar/translate/util"
+)
+
+const BaseURL = "http://localhost:5000"
+
+type LibreTranslate struct {
+	client   *http.Client
+	handler  middleware.Handler
+	glossary util.Glossary
+	onTrans  func([]string) error
+	Name     string
+	apiKey   string
+	baseURL  string
+	format   string
+	cache    *util.Cache
+
+	mu        sync.Mutex
+	languages []language        // /languages的结果，首次翻译时获取
+	codes     map[string]string // --fromlang、--tolang => 服务端语言代码
+}
+
+type option func(*LibreTranslate) error
+
+func New(sc *config.ServiceConfig, opts ...option) (*LibreTranslate, error) {
+	service := sc.Name
+	baseURL := sc.GetEnvValue("base-url")
+	if baseURL == "" {
+		baseURL = BaseURL
+	}
+	format := sc.GetEnvValue("format")
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "html" {
+		return nil, fmt.Errorf("error creating libretranslate translator: invalid format %q, expect text or html", format)
+	}
+
+	l := &LibreTranslate{
+		client:  &http.Client{},
+		Name:    service,
+		apiKey:  sc.GetEnvValue("api-key"),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		format:  format,
+		codes:   make(map[string]string),
+	}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, fmt.Errorf("error creating libretranslate translator: %w", err)
+		}
+	}
+	if cache, err := util.NewCache(service, 20*time.Minute); err == nil {
+		l.cache = cache
+	} else {
+		return nil, fmt.Errorf("error creating libretranslate translator: %w", err)
+	}
+
+	chain := middleware.Chain(
+		middleware.TextsLimit(5000),
+		middleware.OnTranslated(&l.onTrans),
+		middleware.PassThrough(),
+		middleware.Glossary(l.glossary),
+		middleware.Retry(5, 2),
+		middleware.Cache(l.cache),
+		middleware.RateLimit(sc.GetRpmOrDefault(0)), // 自建服务默认不限流
+		middleware.Concurrent(sc.GetMaxConcurrency()),
+	)
+	l.handler = chain(l.translate)
+
+	return l, nil
+}
+
+func WithProxy(proxy string) option {
+	return func(l *LibreTranslate) error {
+		return util.SetProxy(proxy, l.client)
+	}
+}
+
+func WithGlossary(glossary util.Glossary) option {
+	return func(l *LibreTranslate) error {
+		l.glossary = glossary
+		return nil
+	}
+}
+
+type translateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	APIKey string   `json:"api_key,omitempty"`
+}
+
+type translateResponse struct {
+	TranslatedText []string `json:"translatedText"`
+}
+
+func (l *LibreTranslate) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	// 语言已在TranslateContext中校验
+	l.mu.Lock()
+	source, target := l.codes[fromLang], l.codes[toLang]
+	l.mu.Unlock()
+	if source == "" {
+		source = "auto"
+	}
+
+	reqBody := translateRequest{
+		Q:      texts,
+		Source: source,
+		Target: target,
+		Format: l.format,
+		APIKey: l.apiKey,
+	}
+	var data translateResponse
+	if err := l.doJSON(ctx, "POST", "/translate", reqBody, &data); err != nil {
+		return nil, err
+	}
+	if len(data.TranslatedText) != len(texts) {
+		return nil, fmt.Errorf("error translating: %w, expected %d, got %d",
+			transerrors.ErrCountMismatch, len(texts), len(data.TranslatedText))
+	}
+	return data.TranslatedText, nil
+}
+
+type language struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Targets []string `json:"targets"`
+}
+
+// checkLangs 用/languages校验源语言和目标语言，并记下对应的服务端语言代码。
+// 目标语言须在源语言的targets中；自动检测源语言时，须是某个语言的target。旧版服务端没有targets，只校验语言代码
+func (l *LibreTranslate) checkLangs(ctx context.Context, fromLang, toLang string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.languages == nil { // 只获取一次
+		var langs []language
+		if err := l.doJSON(ctx, "GET", "/languages", nil, &langs); err != nil {
+			return fmt.Errorf("error getting languages: %w", err)
+		}
+		l.languages = langs
+	}
+
+	codes := make([]string, 0, len(l.languages))
+	var targets []string
+	for _, lang := range l.languages {
+		codes = append(codes, lang.Code)
+		for _, t := range lang.Targets {
+			if !slices.Contains(targets, t) {
+				targets = append(targets, t)
+			}
+		}
+	}
+	if fromLang != "" {
+		source, ok := matchLang(fromLang, codes)
+		if !ok {
+			return fmt.Errorf("unsupported source language %s, supported: %s", fromLang, strings.Join(codes, ", "))
+		}
+		l.codes[fromLang] = source
+		targets = l.languages[slices.Index(codes, source)].Targets
+	}
+	if len(targets) == 0 {
+		targets = codes
+	}
+
+	target, ok := matchLang(toLang, targets)
+	if !ok {
+		if fromLang != "" {
+			return fmt.Errorf("unsupported target language %s for source language %s, supported: %s", toLang, fromLang, strings.Join(targets, ", "))
+		}
+		return fmt.Errorf("unsupported target language %s, supported: %s", toLang, strings.Join(targets, ", "))
+	}
+	l.codes[toLang] = target
+	return nil
+}
+
+// matchLang 依次尝试完全匹配、简繁中文别名、去掉地区后的匹配
+func matchLang(lang string, codes []string) (string, bool) {
+	candidates := []string{lang}
+	switch strings.ToLower(lang) {
+	case "zh-cn", "zh-sg":
+		candidates = append(candidates, "zh-Hans")
+	case "zh-tw", "zh-hk":
+		candidates = append(candidates, "zh-Hant")
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	candidates = append(candidates, base)
+
+	for _, c := range candidates {
+		for _, code := range codes {
+			if strings.EqualFold(c, code) {
+				return code, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (l *LibreTranslate) doJSON(ctx context.Context, method, path string, reqBody, respData any) error {
+	var body io.Reader
+	if reqBody != nil {
+		reqJSON, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("error marshaling request: %w", err)
+		}
+		body = bytes.NewReader(reqJSON)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, l.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return transerrors.ErrTooManyRequests
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			return fmt.Errorf("API error: %d - %s", resp.StatusCode, e.Error)
+		}
+		return fmt.Errorf("API error: %d - %s", resp.StatusCode, string(respBody))
+	}
+
+	if err := json.Unmarshal(respBody, respData); err != nil {
+		return fmt.Errorf("error unmarshalling JSON: %w, resp body: %s", err, string(respBody))
+	}
+	return nil
+}
+
+func (l *LibreTranslate) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return l.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (l *LibreTranslate) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	if err := l.checkLangs(ctx, fromLang, toLang); err != nil {
+		return nil, err
+	}
+	return l.handler(ctx, texts, fromLang, toLang)
+}
+
+func (l *LibreTranslate) OnTranslated(f func([]string) error) {
+	l.onTrans = f
+}
+
+func (l *LibreTranslate) Close() error {
+	return l.cache.Close()
+}
@@ -0,0 +1,132 @@
+package libretranslate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+)
+
+// newTestLibre 创建指向stand-in服务的LibreTranslate翻译器，译文为原文的大写
+func newTestLibre(t *testing.T, env map[string]string, check func(req translateRequest)) *LibreTranslate {
+	t.Helper()
+	loaded := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/languages":
+			if loaded {
+				t.Error("languages requested more than once")
+			}
+			loaded = true
+			json.NewEncoder(w).Encode([]language{
+				{Code: "en", Name: "English", Targets: []string{"de", "zh-Hans"}},
+				{Code: "de", Name: "German", Targets: []string{"en"}},
+				{Code: "zh-Hans", Name: "Chinese (Simplified)", Targets: []string{"en"}},
+			})
+		case "/translate":
+			var req translateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+			check(req)
+			var resp translateResponse
+			for _, q := range req.Q {
+				resp.TranslatedText = append(resp.TranslatedText, strings.ToUpper(q))
+			}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("TMPDIR", t.TempDir()) // 隔离缓存文件
+	t.Setenv("LIBRETRANSLATE_BASE_URL", server.URL)
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	l, err := New(config.NewServiceConfig("libretranslate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestTranslate(t *testing.T) {
+	l := newTestLibre(t, map[string]string{
+		"LIBRETRANSLATE_API_KEY": "secret",
+		"LIBRETRANSLATE_FORMAT":  "html",
+	}, func(req translateRequest) {
+		if req.Target != "zh-Hans" || req.Source != "auto" || req.Format != "html" || req.APIKey != "secret" {
+			t.Errorf("unexpected request %+v", req)
+		}
+	})
+
+	got, err := l.Translate([]string{"hello", "<b>world</b>"}, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "HELLO" || got[1] != "<B>WORLD</B>" {
+		t.Errorf("unexpected result %q", got)
+	}
+}
+
+func TestTranslate_UnsupportedLang(t *testing.T) {
+	l := newTestLibre(t, nil, func(req translateRequest) {
+		t.Errorf("unexpected translate request %+v", req)
+	})
+
+	_, err := l.Translate([]string{"hello"}, "", "ja")
+	if err == nil || !strings.Contains(err.Error(), "unsupported target language ja") {
+		t.Errorf("expected unsupported language error, got %v", err)
+	}
+}
+
+func TestTranslate_SourceTargets(t *testing.T) {
+	l := newTestLibre(t, nil, func(req translateRequest) {
+		if req.Source != "en" || req.Target != "zh-Hans" {
+			t.Errorf("unexpected request %+v", req)
+		}
+	})
+
+	if _, err := l.Translate([]string{"hello"}, "en", "zh-CN"); err != nil {
+		t.Fatal(err)
+	}
+	// 德语不能译成中文，在进入重试和缓存前报错
+	_, err := l.Translate([]string{"hallo"}, "de", "zh-CN")
+	if err == nil || !strings.Contains(err.Error(), "unsupported target language zh-CN for source language de") {
+		t.Errorf("expected unsupported target language error, got %v", err)
+	}
+	if errors.Is(err, transerrors.ErrMaxRetries) {
+		t.Errorf("unsupported language should not be retried, got %v", err)
+	}
+}
+
+func TestMatchLang(t *testing.T) {
+	t.Parallel()
+
+	codes := []string{"en", "de", "zh", "zh-Hant", "pt-BR"}
+	tests := map[string]string{
+		"de":    "de",
+		"DE":    "de",
+		"de-AT": "de",
+		"zh-CN": "zh",
+		"zh-TW": "zh-Hant",
+		"pt-BR": "pt-BR",
+	}
+	for lang, want := range tests {
+		if got, ok := matchLang(lang, codes); !ok || got != want {
+			t.Errorf("matchLang(%q) = %q, %v, want %q", lang, got, ok, want)
+		}
+	}
+	if _, ok := matchLang("ja", codes); ok {
+		t.Error("matchLang(ja) should not match")
+	}
+}
@@ -0,0 +1,60 @@
+// Package llm 大模型翻译服务共用的响应解析
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+)
+
+// ParseResponse 解析LLM按prompt.txt约定返回的json，按id放回原位
+func ParseResponse(str string, expectedCount int) ([]string, error) {
+	trans := []config.Translation{}
+	if err := json.Unmarshal([]byte(stripCodeFence(str)), &trans); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w, response str: %s", transerrors.ErrInvalidJSON, str)
+	}
+
+	// 验证返回的段数是否与期望的段数匹配
+	if len(trans) != expectedCount {
+		return nil, fmt.Errorf("error parsing response: %w, expected %d, got %d", transerrors.ErrCountMismatch, expectedCount, len(trans))
+	}
+
+	arr := make([]string, len(trans))
+	for _, t := range trans {
+		if t.ID < 0 || t.ID >= len(arr) {
+			return nil, fmt.Errorf("error parsing response: %w, invalid id %d", transerrors.ErrInvalidJSON, t.ID)
+		}
+		arr[t.ID] = t.Text
+	}
+	return arr, nil
+}
+
+// ParseTranslations 解析响应得到texts的译文；较长的原文被原样返回时视为没有翻译，交给Retry重试
+func ParseTranslations(str string, texts []string) ([]string, error) {
+	parsed, err := ParseResponse(str, len(texts))
+	if err != nil {
+		return nil, err
+	}
+	for i := range texts {
+		if texts[i] == parsed[i] && len(texts[i]) > 20 {
+			return nil, transerrors.ErrNoTranslation
+		}
+	}
+	return parsed, nil
+}
+
+// 去掉模型有时包裹在json外的```json代码块标记
+func stripCodeFence(str string) string {
+	s := strings.TrimSpace(str)
+	if !strings.HasPrefix(s, "```") {
+		return str
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:] // 跳过语言标记，比如json
+	}
+	return strings.TrimSuffix(strings.TrimSpace(s), "```")
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smilingpoplar/translate/translator/transerrors"
+)
+
+func TestParseResponse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		str     string
+		count   int
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "reorder by id",
+			str:   `[{"id":1,"text":"b"},{"id":0,"text":"a"}]`,
+			count: 2,
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "code fence",
+			str:   "```json\n[{\"id\":0,\"text\":\"a\"}]\n```",
+			count: 1,
+			want:  []string{"a"},
+		},
+		{
+			name:    "invalid json",
+			str:     `not json`,
+			count:   1,
+			wantErr: transerrors.ErrInvalidJSON,
+		},
+		{
+			name:    "count mismatch",
+			str:     `[{"id":0,"text":"a"}]`,
+			count:   2,
+			wantErr: transerrors.ErrCountMismatch,
+		},
+		{
+			name:    "invalid id",
+			str:     `[{"id":-1,"text":"a"}]`,
+			count:   1,
+			wantErr: transerrors.ErrInvalidJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseResponse(tt.str, tt.count)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ParseResponse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResponse() unexpected error: %v", err)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseResponse() = %q, want %q", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseTranslations(t *testing.T) {
+	t.Parallel()
+
+	long := "This sentence is longer than twenty bytes."
+	_, err := ParseTranslations(`[{"id":0,"text":"`+long+`"}]`, []string{long})
+	if !errors.Is(err, transerrors.ErrNoTranslation) {
+		t.Errorf("ParseTranslations() error = %v, want %v", err, transerrors.ErrNoTranslation)
+	}
+
+	// 短文本如专有名词可以原样保留
+	got, err := ParseTranslations(`[{"id":0,"text":"OK"},{"id":1,"text":"你好"}]`, []string{"OK", "Hello"})
+	if err != nil {
+		t.Fatalf("ParseTranslations() unexpected error: %v", err)
+	}
+	if got[0] != "OK" || got[1] != "你好" {
+		t.Errorf("ParseTranslations() = %q, want [OK 你好]", got)
+	}
+}
@@ -1,39 +1,39 @@
 package middleware
 
 import (
+	"context"
+
 	"github.com/smilingpoplar/translate/util"
 )
 
 func Cache(c *util.Cache) Middleware {
 	return func(handler Handler) Handler {
-		return func(texts []string, toLang string) ([]string, error) {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 			results := make([]string, len(texts))
+			lang := toLang // 指定源语言时按语言对缓存
+			if fromLang != "" {
+				lang = fromLang + ">" + toLang
+			}
 
-			// 检查缓存，收集未缓存的文本
-			uncached := make(map[int]string) // 索引 => 文本
+			// 检查缓存，按原有顺序收集未缓存的文本
+			var indices []int
+			var textsToTranslate []string
 			for i, text := range texts {
-				if cached, found := c.Get(toLang, text); found {
+				if cached, found := c.Get(lang, text); found {
 					results[i] = cached
 				} else {
-					uncached[i] = text
+					indices = append(indices, i)
+					textsToTranslate = append(textsToTranslate, text)
 				}
 			}
 
 			// 所有文本都已缓存，直接返回
-			if len(uncached) == 0 {
+			if len(indices) == 0 {
 				return results, nil
 			}
 
-			// 构建待翻译文本列表
-			indices := make([]int, 0, len(uncached))
-			textsToTranslate := make([]string, 0, len(uncached))
-			for idx, text := range uncached {
-				indices = append(indices, idx)
-				textsToTranslate = append(textsToTranslate, text)
-			}
-
 			// 调用翻译服务
-			translatedTexts, err := handler(textsToTranslate, toLang)
+			translatedTexts, err := handler(ctx, textsToTranslate, fromLang, toLang)
 			if err != nil {
 				return nil, err
 			}
@@ -43,7 +43,7 @@ func Cache(c *util.Cache) Middleware {
 				idx, text := indices[i], textsToTranslate[i]
 				results[idx] = translated
 				if text != translated {
-					c.Set(toLang, text, translated)
+					c.Set(lang, text, translated)
 				}
 			}
 
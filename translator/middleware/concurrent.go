@@ -1,5 +1,7 @@
 package middleware
 
+import "context"
+
 func Concurrent(maxConcurrency int) Middleware {
 	if maxConcurrency <= 0 {
 		return func(handler Handler) Handler {
@@ -9,11 +11,15 @@ func Concurrent(maxConcurrency int) Middleware {
 
 	semaphore := make(chan struct{}, maxConcurrency)
 	return func(handler Handler) Handler {
-		return func(texts []string, toLang string) ([]string, error) {
-			semaphore <- struct{}{}
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 			defer func() { <-semaphore }()
 
-			return handler(texts, toLang)
+			return handler(ctx, texts, fromLang, toLang)
 		}
 	}
 }
@@ -1,33 +1,36 @@
 package middleware
 
 import (
+	"context"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/smilingpoplar/translate/util"
 )
 
-var placeholderRegex = regexp.MustCompile(`(?i)\{id_\d+\}`)
+var placeholderRegex = regexp.MustCompile(`(?i)\{id_(\d+)\}`)
 
-func Glossary(terms map[string]string) Middleware {
+// Glossary 用占位符保护术语，翻译后替换为术语在目标语言下的译文；
+// 目标语言的译文为空的术语不翻译，还原为原文；没有目标语言列的术语照常翻译。
+// 术语按条目的匹配方式查找，正则术语的译文可以引用捕获组
+func Glossary(glossary util.Glossary) Middleware {
 	return func(handler Handler) Handler {
 		type termInfo struct {
-			from  string
-			to    string
-			regex *regexp.Regexp
+			from    string
+			matcher *util.TermMatcher
 		}
 
-		termList := make([]termInfo, 0, len(terms))
-		for from, to := range terms {
-			regex, err := util.BuildWordBoundaryRegex(from)
+		termList := make([]termInfo, 0, len(glossary))
+		for from, entry := range glossary {
+			matcher, err := util.NewTermMatcher(from, entry)
 			if err != nil {
 				continue // 跳过无法编译的术语
 			}
 			termList = append(termList, termInfo{
-				from:  from,
-				to:    to,
-				regex: regex,
+				from:    from,
+				matcher: matcher,
 			})
 		}
 
@@ -36,11 +39,11 @@ func Glossary(terms map[string]string) Middleware {
 			return len(termList[i].from) > len(termList[j].from)
 		})
 
-		return func(texts []string, toLang string) ([]string, error) {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 			// 阶段1：替换原文为占位符
-			termToPlaceholder := make(map[string]string)          // 术语原文 => 占位符
-			placeholderToTranslation := make(map[string]string)   // 占位符 => 译文
-			nextID := 0
+			termToPlaceholder := make(map[string]string)        // 术语原文和译文 => 占位符
+			placeholderToTranslation := make(map[string]string) // 占位符 => 译文
+			nextID := nextPlaceholderID(texts)                  // 避开原文已有的占位符，比如文档格式保护的行内代码
 			textsWithPlaceholders := make([]string, len(texts))
 			sourceTranslationsByText := make([][]string, len(texts))
 
@@ -48,16 +51,41 @@ func Glossary(terms map[string]string) Middleware {
 				processedText := text
 
 				for _, term := range termList {
-					if term.regex.MatchString(processedText) {
-						placeholder, exists := termToPlaceholder[term.from]
+					to, ok := glossary.Translation(term.from, toLang)
+					if !ok { // 术语表没有目标语言的列，交给翻译服务
+						continue
+					}
+					locs := term.matcher.FindAll(processedText)
+					if len(locs) == 0 {
+						continue
+					}
+					placeholderLocs := placeholderRegex.FindAllStringIndex(processedText, -1)
+
+					var b strings.Builder
+					last := 0
+					for _, loc := range locs {
+						if overlapsAny(loc, placeholderLocs) { // 不匹配已替换的占位符
+							continue
+						}
+						translation := term.matcher.Expand(to, processedText, loc)
+						if to == "" { // 没有译文：按原文中的写法还原
+							translation = processedText[loc[0]:loc[1]]
+						}
+						// 同一术语的不同译文（正则展开、原文写法不同）用不同占位符
+						key := term.from + "\x00" + translation
+						placeholder, exists := termToPlaceholder[key]
 						if !exists {
 							placeholder = util.GeneratePlaceholder(nextID)
 							nextID++
-							termToPlaceholder[term.from] = placeholder
-							placeholderToTranslation[canonicalPlaceholder(placeholder)] = term.to
+							termToPlaceholder[key] = placeholder
+							placeholderToTranslation[canonicalPlaceholder(placeholder)] = translation
 						}
-						processedText = term.regex.ReplaceAllString(processedText, placeholder)
+						b.WriteString(processedText[last:loc[0]])
+						b.WriteString(placeholder)
+						last = loc[1]
 					}
+					b.WriteString(processedText[last:])
+					processedText = b.String()
 				}
 
 				textsWithPlaceholders[i] = processedText
@@ -65,14 +93,19 @@ func Glossary(terms map[string]string) Middleware {
 			}
 
 			// 阶段2：翻译（调用下一个中间件）
-			result, err := handler(textsWithPlaceholders, toLang)
+			result, err := handler(ctx, textsWithPlaceholders, fromLang, toLang)
 			if err != nil {
 				return nil, err
 			}
 
-			// 阶段3：替换占位符为译文
+			// 阶段3：替换占位符为译文。占位符都是原文中的ID时按ID还原，模型可能调换了顺序；
+			// 否则模型改写了编号，按出现的先后还原
 			for i := range result {
-				result[i] = replacePlaceholdersByOrder(result[i], sourceTranslationsByText[i])
+				if hasOnlyKnownPlaceholders(result[i], textsWithPlaceholders[i]) {
+					result[i] = replacePlaceholdersByID(result[i], placeholderToTranslation)
+				} else {
+					result[i] = replacePlaceholdersByOrder(result[i], sourceTranslationsByText[i])
+				}
 			}
 
 			return result, nil
@@ -80,6 +113,30 @@ func Glossary(terms map[string]string) Middleware {
 	}
 }
 
+// hasOnlyKnownPlaceholders 译文中的占位符是否都出现在原文中
+func hasOnlyKnownPlaceholders(translatedText, sourceText string) bool {
+	known := make(map[string]bool)
+	for _, token := range placeholderRegex.FindAllString(sourceText, -1) {
+		known[canonicalPlaceholder(token)] = true
+	}
+	for _, token := range placeholderRegex.FindAllString(translatedText, -1) {
+		if !known[canonicalPlaceholder(token)] {
+			return false
+		}
+	}
+	return true
+}
+
+// replacePlaceholdersByID 按ID还原术语占位符，不是术语的占位符（如文档格式保护的行内代码）原样保留
+func replacePlaceholdersByID(translatedText string, placeholderToTranslation map[string]string) string {
+	return placeholderRegex.ReplaceAllStringFunc(translatedText, func(token string) string {
+		if translation, ok := placeholderToTranslation[canonicalPlaceholder(token)]; ok {
+			return translation
+		}
+		return token
+	})
+}
+
 func replacePlaceholdersByOrder(translatedText string, sourceTranslations []string) string {
 	index := 0
 	return placeholderRegex.ReplaceAllStringFunc(translatedText, func(token string) string {
@@ -107,6 +164,28 @@ func collectSourceTranslations(sourceText string, placeholderToTranslation map[s
 	return translations
 }
 
+func nextPlaceholderID(texts []string) int {
+	next := 0
+	for _, text := range texts {
+		for _, m := range placeholderRegex.FindAllStringSubmatch(text, -1) {
+			if id, err := strconv.Atoi(m[1]); err == nil && id >= next {
+				next = id + 1
+			}
+		}
+	}
+	return next
+}
+
+// overlapsAny loc是否与spans中的任一区间重叠
+func overlapsAny(loc []int, spans [][]int) bool {
+	for _, span := range spans {
+		if loc[0] < span[1] && span[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
 func canonicalPlaceholder(token string) string {
 	return strings.ToUpper(token)
 }
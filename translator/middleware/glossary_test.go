@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"context"
 	"regexp"
 	"strconv"
 	"testing"
+
+	"github.com/smilingpoplar/translate/util"
 )
 
 // TestGlossary_BasicTermProtection 测试基本术语保护
@@ -12,13 +15,13 @@ func TestGlossary_BasicTermProtection(t *testing.T) {
 		"AWS": "Amazon Web Services",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		// 模拟翻译，保持占位符不变
 		return texts, nil
 	})
 
 	input := []string{"AWS is a cloud platform"}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -38,7 +41,7 @@ func TestGlossary_MultipleTerms(t *testing.T) {
 		"Kubernetes": "Kubernetes",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
@@ -46,7 +49,7 @@ func TestGlossary_MultipleTerms(t *testing.T) {
 		"AWS is a cloud platform",
 		"Use Docker and Kubernetes to deploy applications",
 	}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -70,7 +73,7 @@ func TestGlossary_WordBoundaryMatching(t *testing.T) {
 		"API": "API",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
@@ -80,7 +83,7 @@ func TestGlossary_WordBoundaryMatching(t *testing.T) {
 		"The API is great",
 	}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -102,12 +105,12 @@ func TestGlossary_OverlappingTerms(t *testing.T) {
 		"机器学习模型": "机器学习模型",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
 	input := []string{"开发AI模型和AI应用，以及机器学习模型"}
-	result, err := handler(input, "en")
+	result, err := handler(context.Background(), input, "", "en")
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -131,12 +134,12 @@ func TestGlossary_OverlappingTerms(t *testing.T) {
 func TestGlossary_EmptyGlossary(t *testing.T) {
 	terms := map[string]string{}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
 	input := []string{"AWS is a cloud platform"}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -152,12 +155,12 @@ func TestGlossary_EmptyGlossary(t *testing.T) {
 func TestGlossary_NilGlossary(t *testing.T) {
 	var terms map[string]string = nil
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
 	input := []string{"AWS is a cloud platform"}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -175,7 +178,7 @@ func TestGlossary_PunctuationBoundaries(t *testing.T) {
 		"API": "应用程序接口",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
@@ -185,7 +188,7 @@ func TestGlossary_PunctuationBoundaries(t *testing.T) {
 		"(API)",
 	}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -209,13 +212,13 @@ func TestGlossary_CaseInsensitive(t *testing.T) {
 		"AWS": "Amazon Web Services",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
 	input := []string{"AWS and aws"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -233,7 +236,7 @@ func TestGlossary_MultipleTexts(t *testing.T) {
 		"Docker": "Docker",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
@@ -243,7 +246,7 @@ func TestGlossary_MultipleTexts(t *testing.T) {
 		"Deploy with Docker",
 	}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -267,7 +270,7 @@ func TestGlossary_PlaceholderPreservation(t *testing.T) {
 		"Docker": "Docker",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		// 模拟翻译服务返回的内容（占位符应该保持不变）
 		// 在实际场景中，翻译服务应该保持 {ID_n} 不变
 		return texts, nil
@@ -275,7 +278,7 @@ func TestGlossary_PlaceholderPreservation(t *testing.T) {
 
 	input := []string{"Docker containers are lightweight"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -287,6 +290,31 @@ func TestGlossary_PlaceholderPreservation(t *testing.T) {
 	}
 }
 
+// TestGlossary_ExistingPlaceholders 测试原文已有占位符时，术语占位符不与之冲突
+func TestGlossary_ExistingPlaceholders(t *testing.T) {
+	terms := map[string]string{
+		"Docker": "容器引擎",
+	}
+
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		if texts[0] != "Run {ID_0} with {ID_1}" {
+			t.Errorf("unexpected text sent for translation: %q", texts[0])
+		}
+		return texts, nil
+	})
+
+	input := []string{"Run {ID_0} with Docker"}
+	result, err := handler(context.Background(), input, "", "zh-CN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Run {ID_0} with 容器引擎"
+	if result[0] != expected {
+		t.Errorf("expected %q, got %q", expected, result[0])
+	}
+}
+
 // TestGlossary_TermWithSpecialCharacters 测试包含特殊字符的术语
 func TestGlossary_TermWithSpecialCharacters(t *testing.T) {
 	terms := map[string]string{
@@ -294,13 +322,13 @@ func TestGlossary_TermWithSpecialCharacters(t *testing.T) {
 		"C#":  "C#",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
 	input := []string{"Learn C++ and C# programming"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -318,13 +346,13 @@ func TestGlossary_NoMatchInText(t *testing.T) {
 		"AWS": "Amazon Web Services",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
 	input := []string{"Google is also a cloud platform"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -342,13 +370,13 @@ func TestGlossary_LongerTermPriority(t *testing.T) {
 		"Machine":          "机器",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return texts, nil
 	})
 
 	input := []string{"Machine Learning is a subset of Machine"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -368,7 +396,7 @@ func TestGlossary_SameTranslationShouldUseDifferentPlaceholders(t *testing.T) {
 		"Amazon Web Services": "Amazon Web Services",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		text := texts[0]
 		// 两个术语项都命中时应有2个唯一占位符
 		uniqueCount := countUniquePlaceholders(text)
@@ -380,7 +408,7 @@ func TestGlossary_SameTranslationShouldUseDifferentPlaceholders(t *testing.T) {
 
 	input := []string{"AWS and Amazon Web Services are the same"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -414,7 +442,7 @@ func TestGlossary_ThreeTermsWithSharedTranslationsShouldUseThreePlaceholders(t *
 		"Docker":              "Docker",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		text := texts[0]
 		// 三个术语项都命中时应有3个唯一占位符
 		uniqueCount := countUniquePlaceholders(text)
@@ -426,7 +454,7 @@ func TestGlossary_ThreeTermsWithSharedTranslationsShouldUseThreePlaceholders(t *
 
 	input := []string{"AWS, Amazon Web Services and Docker"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -447,7 +475,7 @@ func TestGlossary_PlaceholderIdShouldNotExceedTermCount(t *testing.T) {
 		"DynamoDB": "DynamoDB",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		text := texts[0]
 		// 5 个术语项都命中时，应对应 5 个占位符（ID 范围 0-4）
 		matches := regexp.MustCompile(`\{ID_(\d+)\}`).FindAllStringSubmatch(text, -1)
@@ -471,7 +499,7 @@ func TestGlossary_PlaceholderIdShouldNotExceedTermCount(t *testing.T) {
 
 	input := []string{"AWS, EC2, S3, RDS, and DynamoDB"}
 
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -490,7 +518,7 @@ func TestGlossary_ShouldRecoverWhenModelReturnsUnknownPlaceholderIds(t *testing.
 		"Kubernetes": "Kubernetes",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		// 模拟模型改写占位符编号：
 		// - {ID_10}、{ID_9} 不在本地生成范围
 		// - {id_1} 大小写被改写
@@ -498,7 +526,7 @@ func TestGlossary_ShouldRecoverWhenModelReturnsUnknownPlaceholderIds(t *testing.
 	})
 
 	input := []string{"AWS with Docker and Kubernetes"}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -517,14 +545,14 @@ func TestGlossary_ShouldRestoreByPlaceholderOccurrenceOrder(t *testing.T) {
 		"EC2":    "Elastic Compute Cloud",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		// 模拟模型把三个占位符都改成同一个 token
 		// 回填仍应按 source 中占位符出现顺序恢复：AWS -> Docker -> EC2
 		return []string{"{ID_9} and {ID_9} and {ID_9}"}, nil
 	})
 
 	input := []string{"AWS and Docker and EC2"}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -541,12 +569,12 @@ func TestGlossary_ShouldRemoveExcessPlaceholders(t *testing.T) {
 		"AWS": "Amazon Web Services",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return []string{"{ID_9} and {ID_8}"}, nil
 	})
 
 	input := []string{"AWS and cloud"}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -562,12 +590,12 @@ func TestGlossary_ShouldRemoveHallucinatedPlaceholdersWhenSourceHasNone(t *testi
 		"AWS": "Amazon Web Services",
 	}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return []string{"normal text {ID_42}"}, nil
 	})
 
 	input := []string{"hello world"}
-	result, err := handler(input, "zh-CN")
+	result, err := handler(context.Background(), input, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -581,11 +609,11 @@ func TestGlossary_ShouldRemoveHallucinatedPlaceholdersWhenSourceHasNone(t *testi
 func TestGlossary_EmptyTermsShouldStillCleanHallucinatedPlaceholders(t *testing.T) {
 	terms := map[string]string{}
 
-	handler := Glossary(terms)(func(texts []string, toLang string) ([]string, error) {
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 		return []string{"hello {ID_20} world"}, nil
 	})
 
-	result, err := handler([]string{"hello world"}, "zh-CN")
+	result, err := handler(context.Background(), []string{"hello world"}, "", "zh-CN")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -594,3 +622,166 @@ func TestGlossary_EmptyTermsShouldStillCleanHallucinatedPlaceholders(t *testing.
 		t.Errorf("expected no placeholder left, got %q", result[0])
 	}
 }
+
+// TestGlossary_ChooseTranslationByTargetLang 测试按目标语言选择术语译文
+func TestGlossary_ChooseTranslationByTargetLang(t *testing.T) {
+	glossary := util.Glossary{
+		"cloud": {Targets: map[string]string{"zh-CN": "云", "ja": "クラウド"}},
+	}
+
+	handler := Glossary(glossary)(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		return texts, nil
+	})
+
+	for toLang, expected := range map[string]string{"zh-CN": "use 云", "ja": "use クラウド"} {
+		result, err := handler(context.Background(), []string{"use cloud"}, "", toLang)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result[0] != expected {
+			t.Errorf("toLang %s: expected %q, got %q", toLang, expected, result[0])
+		}
+	}
+}
+
+// TestGlossary_EmptyTranslationShouldKeepSource 测试目标语言没有译文的术语保持原文
+func TestGlossary_EmptyTranslationShouldKeepSource(t *testing.T) {
+	glossary := util.Glossary{
+		"Kubernetes": {Targets: map[string]string{"zh-CN": "", "ja": "クバネティス"}},
+	}
+
+	var sent []string
+	handler := Glossary(glossary)(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		sent = texts
+		return []string{"部署到 {ID_0}，{ID_1} 集群"}, nil
+	})
+
+	result, err := handler(context.Background(), []string{"deploy to Kubernetes, kubernetes cluster"}, "", "zh-CN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 术语仍被占位符保护，不交给翻译
+	if sent[0] != "deploy to {ID_0}, {ID_1} cluster" {
+		t.Errorf("expected term protected by placeholders, got %q", sent[0])
+	}
+	// 按原文中的写法还原
+	expected := "部署到 Kubernetes，kubernetes 集群"
+	if result[0] != expected {
+		t.Errorf("expected %q, got %q", expected, result[0])
+	}
+}
+
+// TestGlossary_MissingColumnShouldTranslate 测试术语表没有目标语言列时，术语交给翻译服务正常翻译
+func TestGlossary_MissingColumnShouldTranslate(t *testing.T) {
+	glossary := util.Glossary{
+		"cloud": {Targets: map[string]string{"zh-CN": "云", "ja": "クラウド"}, Langs: []string{"zh-CN", "ja"}},
+	}
+
+	var sent []string
+	handler := Glossary(glossary)(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		sent = append([]string(nil), texts...)
+		return []string{"le nuage"}, nil
+	})
+
+	result, err := handler(context.Background(), []string{"the cloud"}, "", "fr")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent[0] != "the cloud" {
+		t.Errorf("expected term sent unprotected, got %q", sent[0])
+	}
+	if result[0] != "le nuage" {
+		t.Errorf("expected %q, got %q", "le nuage", result[0])
+	}
+}
+
+// TestGlossary_MatchModes 测试术语的匹配方式：区分大小写、子串、正则捕获组、词形变化、中文
+func TestGlossary_MatchModes(t *testing.T) {
+	glossary := util.Glossary{
+		"Go":           {Targets: map[string]string{"": "Go语言"}, CaseSensitive: true},
+		"Kube":         {Targets: map[string]string{"": "Kube"}, Substring: true},
+		`(\w+)Service`: {Targets: map[string]string{"": "${1}服务"}, Regex: true, CaseSensitive: true},
+		"cluster":      {Targets: map[string]string{"": "集群"}, Inflect: true},
+		"人工智能":         {Targets: map[string]string{"": "AI"}},
+	}
+
+	var sent []string
+	handler := Glossary(glossary)(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		sent = append([]string(nil), texts...)
+		return texts, nil
+	})
+
+	input := []string{
+		"Go and go",
+		"run kubectl on Clusters",
+		"UserService calls OrderService",
+		"使用人工智能",
+	}
+	result, err := handler(context.Background(), input, "", "zh-CN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"Go语言 and go",
+		"run Kubectl on 集群",
+		"User服务 calls Order服务",
+		"使用AI",
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Errorf("text %d: expected %q, got %q (sent %q)", i, expected[i], result[i], sent[i])
+		}
+	}
+	// 正则术语的不同展开结果使用不同占位符
+	if placeholders := regexp.MustCompile(`\{ID_\d+\}`).FindAllString(sent[2], -1); len(placeholders) != 2 || placeholders[0] == placeholders[1] {
+		t.Errorf("expected two different placeholders, got %q", sent[2])
+	}
+}
+
+// TestGlossary_SubstringShouldNotMatchPlaceholders 测试子串匹配不会匹配到占位符内部
+func TestGlossary_SubstringShouldNotMatchPlaceholders(t *testing.T) {
+	glossary := util.Glossary{
+		"AWS": {Targets: map[string]string{"": "亚马逊云"}},
+		"ID":  {Targets: map[string]string{"": "标识"}, Substring: true},
+	}
+
+	handler := Glossary(glossary)(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		return texts, nil
+	})
+
+	result, err := handler(context.Background(), []string{"AWS user ID"}, "", "zh-CN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0] != "亚马逊云 user 标识" {
+		t.Errorf("expected %q, got %q", "亚马逊云 user 标识", result[0])
+	}
+}
+
+// TestGlossary_ShouldRestoreReorderedPlaceholdersByID 测试模型调换占位符顺序时按ID还原，不和文档格式的占位符互换
+func TestGlossary_ShouldRestoreReorderedPlaceholdersByID(t *testing.T) {
+	terms := map[string]string{
+		"Server": "服务器",
+	}
+
+	var sent []string
+	handler := Glossary(util.NewGlossary(terms))(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		sent = append([]string(nil), texts...)
+		return []string{"在{ID_1}上运行{ID_0}"}, nil
+	})
+
+	result, err := handler(context.Background(), []string{"Run {ID_0} on Server"}, "", "zh-CN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent[0] != "Run {ID_0} on {ID_1}" {
+		t.Fatalf("unexpected text sent %q", sent[0])
+	}
+
+	expected := "在服务器上运行{ID_0}"
+	if result[0] != expected {
+		t.Errorf("expected %q, got %q", expected, result[0])
+	}
+}
@@ -1,8 +1,11 @@
 package middleware
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
 
-type Handler func(texts []string, toLang string) ([]string, error)
+type Handler func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error)
 type Middleware func(Handler) Handler
 
 func Chain(m ...Middleware) Middleware {
@@ -14,9 +17,9 @@ func Chain(m ...Middleware) Middleware {
 	}
 }
 
-func TextHandler(fn func(string, string) (string, error)) Handler {
-	return func(texts []string, toLang string) ([]string, error) {
-		result, err := fn(strings.Join(texts, "\n"), toLang)
+func TextHandler(fn func(context.Context, string, string, string) (string, error)) Handler {
+	return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		result, err := fn(ctx, strings.Join(texts, "\n"), fromLang, toLang)
 		return []string{result}, err
 	}
 }
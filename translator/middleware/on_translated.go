@@ -1,18 +1,118 @@
 package middleware
 
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// OnTranslated 在每个分组翻译完成后回调onTrans。
+// 若外层有TextsLimit，结果经重排缓冲区按原文顺序交给onTrans，拆分过的长文本会先拼回再交出；
+// 否则直接交出本组结果。onTrans的调用总是串行的。
 func OnTranslated(onTrans *func([]string) error) Middleware {
+	var mu sync.Mutex
 	return func(handler Handler) Handler {
-		return func(texts []string, toLang string) ([]string, error) {
-			result, err := handler(texts, toLang)
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+			result, err := handler(ctx, texts, fromLang, toLang)
 			if err != nil {
 				return nil, err
 			}
-			if *onTrans != nil {
-				if err = (*onTrans)(result); err != nil {
-					return nil, err
-				}
+			if *onTrans == nil {
+				return result, nil
+			}
+
+			if buf := reorderBufferFrom(ctx); buf != nil {
+				err = buf.put(groupOffsetFrom(ctx), result, *onTrans)
+			} else {
+				mu.Lock()
+				err = (*onTrans)(result)
+				mu.Unlock()
+			}
+			if err != nil {
+				return nil, err
 			}
 			return result, nil
 		}
 	}
 }
+
+// reorderBuffer 收集乱序完成的分组结果，按原文顺序交出已就绪的前缀
+type reorderBuffer struct {
+	mu      sync.Mutex
+	info    *splitInfo
+	results []string
+	done    []bool
+	next    int // 下一个待交出的原文下标
+}
+
+func newReorderBuffer(n int, info *splitInfo) *reorderBuffer {
+	return &reorderBuffer{
+		info:    info,
+		results: make([]string, n),
+		done:    make([]bool, n),
+	}
+}
+
+func (b *reorderBuffer) put(offset int, result []string, emit func([]string) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, text := range result {
+		b.results[offset+i] = text
+		b.done[offset+i] = true
+	}
+
+	var ready []string
+	for b.next < b.info.Len {
+		text, ok := b.merged(b.next)
+		if !ok {
+			break
+		}
+		ready = append(ready, text)
+		b.next++
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+	return emit(ready)
+}
+
+// merged 返回原文下标i的译文，拆分过的长文本需所有片段都完成
+func (b *reorderBuffer) merged(i int) (string, bool) {
+	fragments, ok := b.info.Mapping[i]
+	if !ok {
+		return b.results[i], b.done[i]
+	}
+	parts := make([]string, 0, len(fragments))
+	for _, f := range fragments {
+		if !b.done[f] {
+			return "", false
+		}
+		parts = append(parts, b.results[f])
+	}
+	return strings.Join(parts, "\n"), true
+}
+
+type reorderBufferKey struct{}
+type groupOffsetKey struct{}
+
+// withReorderBuffer 安装新的重排缓冲区，并将分组起始下标归零
+func withReorderBuffer(ctx context.Context, buf *reorderBuffer) context.Context {
+	ctx = context.WithValue(ctx, reorderBufferKey{}, buf)
+	return context.WithValue(ctx, groupOffsetKey{}, 0)
+}
+
+func reorderBufferFrom(ctx context.Context) *reorderBuffer {
+	buf, _ := ctx.Value(reorderBufferKey{}).(*reorderBuffer)
+	return buf
+}
+
+// withGroupOffset 记录当前分组在TextsLimit拆分后文本中的起始下标，嵌套分组时累加
+func withGroupOffset(ctx context.Context, offset int) context.Context {
+	return context.WithValue(ctx, groupOffsetKey{}, groupOffsetFrom(ctx)+offset)
+}
+
+func groupOffsetFrom(ctx context.Context) int {
+	offset, _ := ctx.Value(groupOffsetKey{}).(int)
+	return offset
+}
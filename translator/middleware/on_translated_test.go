@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOnTranslated_EmitsInInputOrder 测试分组并发乱序完成时，仍按原文顺序交出且与最终结果一致
+func TestOnTranslated_EmitsInInputOrder(t *testing.T) {
+	t.Parallel()
+
+	var emitted []string
+	var mu sync.Mutex
+	inEmit := false
+	onTrans := func(lines []string) error {
+		mu.Lock()
+		if inEmit {
+			t.Error("onTrans called concurrently")
+		}
+		inEmit = true
+		mu.Unlock()
+
+		emitted = append(emitted, lines...)
+
+		mu.Lock()
+		inEmit = false
+		mu.Unlock()
+		return nil
+	}
+
+	// 越靠前的分组完成得越晚
+	translate := func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		delay := 20 * time.Millisecond
+		if len(texts) > 0 && strings.HasPrefix(texts[0], "a") {
+			delay = 60 * time.Millisecond
+		}
+		time.Sleep(delay)
+		result := make([]string, len(texts))
+		for i, text := range texts {
+			result[i] = strings.ToUpper(text)
+		}
+		return result, nil
+	}
+
+	handler := Chain(
+		TextsLimit(8),
+		OnTranslated(&onTrans),
+	)(translate)
+
+	input := []string{"aaa", "bbb", "ccc\nddd\neee", "fff", "ggg"}
+	got, err := handler(context.Background(), input, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"AAA", "BBB", "CCC\nDDD\nEEE", "FFF", "GGG"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expect result %q, got %q", want, got)
+	}
+	if !reflect.DeepEqual(emitted, want) {
+		t.Errorf("expect emitted %q, got %q", want, emitted)
+	}
+}
+
+func TestReorderBuffer_WaitsForAllFragments(t *testing.T) {
+	t.Parallel()
+
+	// 下标0被拆分为片段2、3
+	info := &splitInfo{Len: 2, Mapping: map[int][]int{0: {2, 3}}}
+	buf := newReorderBuffer(4, info)
+
+	var emitted []string
+	emit := func(lines []string) error {
+		emitted = append(emitted, lines...)
+		return nil
+	}
+
+	if err := buf.put(0, []string{"", "B"}, emit); err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.put(3, []string{"A2"}, emit); err != nil {
+		t.Fatal(err)
+	}
+	if len(emitted) != 0 {
+		t.Fatalf("expect nothing emitted before all fragments done, got %q", emitted)
+	}
+	if err := buf.put(2, []string{"A1"}, emit); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"A1\nA2", "B"}
+	if !reflect.DeepEqual(emitted, want) {
+		t.Errorf("expect %q, got %q", want, emitted)
+	}
+}
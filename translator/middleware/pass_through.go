@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/smilingpoplar/translate/util"
+)
+
+// 判断文本已是目标语言所需的最低置信度
+const passThroughConfidence = 0.9
+
+var (
+	// 整行只是一个网址
+	urlLineRe = regexp.MustCompile(`^(?i:(?:https?|ftp)://|www\.)\S+$`)
+	// HTML标签，标签和占位符不算代码
+	tagRe = regexp.MustCompile(`</?[A-Za-z][^<>]*>`)
+	// 代码的特征，整行至少有两种才算代码。单个特征在普通文本中也常见，如Yes/No、e.g.、Total = 5 items
+	codeSignals = []*regexp.Regexp{
+		regexp.MustCompile(`[A-Za-z_]\w*\.[A-Za-z_]\w`),                                             // 成员访问，如fmt.Errorf
+		regexp.MustCompile(`\w\(`),                                                                  // 函数调用，如compute(
+		regexp.MustCompile(`[A-Za-z0-9]_[A-Za-z0-9]`),                                               // snake_case
+		regexp.MustCompile(`::|->|=>|==|!=|&&|\|\|`),                                                // 运算符
+		regexp.MustCompile(`^[A-Za-z_$][\w.$\[\]]*\s*(?::=|[-+*/]?=)\s*\S`),                         // 赋值，如x = 1、count += 2
+		regexp.MustCompile(`[;{}]$`),                                                                // 语句结尾，如foo(bar);、if (x) {
+		regexp.MustCompile(`^(?:if|for|while|switch|return|func|def|class|import|const|let|var)\b`), // 小写的关键字开头
+		regexp.MustCompile(`[a-z][A-Z]\w`),                                                          // camelCase
+		regexp.MustCompile(`^(?:~|\.{1,2})?/[\w.-]+/`),                                              // 路径，如/usr/bin
+		regexp.MustCompile(`(?:^|\s)--[a-z][\w-]*`),                                                 // 命令行选项，如--flag
+	}
+	// 自然语言的单词，可以带一个句读符号
+	proseWordRe = regexp.MustCompile(`^\pL{2,}[.,:;!?]?$`)
+)
+
+type passThroughDisabledKey struct{}
+
+// WithoutPassThrough 返回关闭PassThrough的ctx，所有文本都交给翻译服务
+func WithoutPassThrough(ctx context.Context) context.Context {
+	return context.WithValue(ctx, passThroughDisabledKey{}, true)
+}
+
+// PassThrough 离线判断无需翻译的文本，原样返回而不交给下一个处理器：
+// 已是目标语言的文本，以及纯数字、网址、代码。用WithoutPassThrough(ctx)关闭
+func PassThrough() Middleware {
+	return func(handler Handler) Handler {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+			if disabled, _ := ctx.Value(passThroughDisabledKey{}).(bool); disabled {
+				return handler(ctx, texts, fromLang, toLang)
+			}
+			var indices []int
+			var pending []string
+			for i, text := range texts {
+				if !skipTranslation(text, toLang) {
+					indices = append(indices, i)
+					pending = append(pending, text)
+				}
+			}
+			if len(pending) == len(texts) {
+				return handler(ctx, texts, fromLang, toLang)
+			}
+
+			result := make([]string, len(texts))
+			copy(result, texts)
+			if len(pending) == 0 {
+				return result, nil
+			}
+			translated, err := handler(ctx, pending, fromLang, toLang)
+			if err != nil {
+				return nil, err
+			}
+			for j, i := range indices {
+				result[i] = translated[j]
+			}
+			return result, nil
+		}
+	}
+}
+
+func skipTranslation(text, toLang string) bool {
+	text = strings.TrimSpace(text)
+	if !strings.ContainsFunc(text, unicode.IsLetter) { // 空白、数字、符号
+		return true
+	}
+	if urlLineRe.MatchString(text) {
+		return true
+	}
+	plain := strings.TrimSpace(placeholderRegex.ReplaceAllString(tagRe.ReplaceAllString(text, " "), " "))
+	if !strings.ContainsFunc(plain, unicode.IsLetter) { // 只有标签和占位符
+		return true
+	}
+	return isCode(plain) || inLang(text, toLang)
+}
+
+// isCode 按符号判断整行是代码，宁可漏判也不误判普通句子。
+// 提到标识符的句子（如Call os.Open(path) to read the file.）单词占多数，仍要翻译
+func isCode(text string) bool {
+	if strings.HasPrefix(text, "//") || strings.HasPrefix(text, "/*") || strings.HasPrefix(text, "#include") ||
+		strings.HasPrefix(text, "#!") || strings.HasPrefix(text, "$ ") {
+		return true
+	}
+	fields := strings.Fields(text)
+	words := 0
+	for _, f := range fields {
+		if proseWordRe.MatchString(f) {
+			words++
+		}
+	}
+	if words*2 > len(fields) {
+		return false
+	}
+	signals := 0
+	for _, re := range codeSignals {
+		if re.MatchString(text) {
+			signals++
+		}
+	}
+	return signals >= 2
+}
+
+// inLang 离线检测文本已是lang语言，中文还要区分简繁
+func inLang(text, lang string) bool {
+	detected, confidence := util.DetectLang(text)
+	if detected == "" || confidence < passThroughConfidence || !util.MatchLang(lang, []string{detected}) {
+		return false
+	}
+	// 拉丁字母的语言靠三元组模型区分，短文本不可靠
+	if script, _ := util.DetectScriptLang(text); script == "" && len(strings.Fields(text)) < 3 {
+		return false
+	}
+	if detected == "zh" {
+		return util.ChineseVariant(text) == chineseVariantOf(lang)
+	}
+	return true
+}
+
+// chineseVariantOf 中文语言代码对应的简繁写法
+func chineseVariantOf(lang string) string {
+	switch strings.ToLower(strings.ReplaceAll(lang, "_", "-")) {
+	case "zh-tw", "zh-hk", "zh-mo", "zh-hant":
+		return "Hant"
+	}
+	return "Hans"
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPassThrough(t *testing.T) {
+	t.Parallel()
+
+	var sent []string
+	handler := PassThrough()(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		sent = append(sent, texts...)
+		result := make([]string, len(texts))
+		for i, text := range texts {
+			result[i] = "<" + text + ">"
+		}
+		return result, nil
+	})
+
+	texts := []string{
+		"Hello, world",
+		"12345",
+		"  ",
+		"https://example.com/docs?page=1",
+		"config.load_file()",
+		"x := compute(a, b)",
+		"return fmt.Errorf(\"bad\");",
+		"这是已经翻译好的中文句子",
+		"這是繁體中文的句子",
+		"For example: see the docs.",
+		"<b>world</b>",
+		"{ID_0} {ID_1}",
+	}
+	got, err := handler(context.Background(), texts, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"<Hello, world>",
+		"12345",
+		"  ",
+		"https://example.com/docs?page=1",
+		"config.load_file()",
+		"x := compute(a, b)",
+		"return fmt.Errorf(\"bad\");",
+		"这是已经翻译好的中文句子",
+		"<這是繁體中文的句子>",
+		"<For example: see the docs.>",
+		"<<b>world</b>>",
+		"{ID_0} {ID_1}",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+	wantSent := []string{"Hello, world", "這是繁體中文的句子", "For example: see the docs.", "<b>world</b>"}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("sent %q, want %q", sent, wantSent)
+	}
+}
+
+func TestPassThroughTargetLang(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		toLang string
+		want   bool
+	}{
+		{"The quick brown fox jumps over the lazy dog", "en", true},
+		{"The quick brown fox jumps over the lazy dog", "de", false},
+		{"Settings", "en", false}, // 短文本不可靠
+		{"Ich habe heute keine Zeit, weil ich arbeiten muss", "de", true},
+		{"こんにちは、世界", "ja", true},
+		{"这是简体中文", "zh-TW", false},
+		{"這是繁體中文", "zh-TW", true},
+		{"Release notes", "zh-CN", false},
+	}
+	for _, tt := range tests {
+		if got := skipTranslation(tt.text, tt.toLang); got != tt.want {
+			t.Errorf("skipTranslation(%q, %s) = %v, want %v", tt.text, tt.toLang, got, tt.want)
+		}
+	}
+}
+
+func TestPassThroughCode(t *testing.T) {
+	t.Parallel()
+
+	code := []string{
+		"config.load_file()",
+		"x := compute(a, b)",
+		"foo(bar);",
+		"if (x) {",
+		"x = 1;",
+		"userName.trim()",
+		"// TODO: remove",
+	}
+	for _, text := range code {
+		if !skipTranslation(text, "zh-CN") {
+			t.Errorf("skipTranslation(%q) = false, want true", text)
+		}
+	}
+
+	// 只有单个代码特征的普通文本，或夹着代码的句子，译成其他语言时不能跳过
+	texts := []string{
+		"Yes/No",
+		"e.g.",
+		"Total = 5 items",
+		"See the manual (chapter 3);",
+		"Read/write access",
+		"Visit example.com for details",
+		"Use the iPhone app",
+		"snake_case",
+		// 提到标识符的句子
+		"Call os.Open(path) to read the file.",
+		"Set max_retries in config.yaml before starting.",
+		"Use the --verbose flag with fmt.Println output.",
+	}
+	for _, text := range texts {
+		for _, toLang := range []string{"zh-CN", "de", "ja", "fr"} {
+			if skipTranslation(text, toLang) {
+				t.Errorf("skipTranslation(%q, %s) = true, want false", text, toLang)
+			}
+		}
+	}
+}
+
+func TestWithoutPassThrough(t *testing.T) {
+	t.Parallel()
+
+	var sent []string
+	handler := PassThrough()(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		sent = texts
+		return texts, nil
+	})
+
+	texts := []string{"12345", "这是已经翻译好的中文句子", "config.load_file()"}
+	if _, err := handler(WithoutPassThrough(context.Background()), texts, "", "zh-CN"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sent, texts) {
+		t.Errorf("sent %q, want all texts %q", sent, texts)
+	}
+}
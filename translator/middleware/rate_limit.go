@@ -7,15 +7,21 @@ import (
 )
 
 func RateLimit(rpm int) Middleware {
+	if rpm <= 0 { // 0 表示不限制
+		return func(handler Handler) Handler {
+			return handler
+		}
+	}
+
 	burst := max(rpm / 30, 10)
 	limiter := rate.NewLimiter(rate.Limit(rpm)/60, burst)
 
 	return func(next Handler) Handler {
-		return func(texts []string, toLang string) ([]string, error) {
-			if err := limiter.Wait(context.Background()); err != nil {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+			if err := limiter.Wait(ctx); err != nil {
 				return nil, err
 			}
-			return next(texts, toLang)
+			return next(ctx, texts, fromLang, toLang)
 		}
 	}
 }
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/smilingpoplar/translate/translator/transerrors"
@@ -9,26 +11,55 @@ import (
 
 func Retry(retryCount, baseDelay int) Middleware {
 	return func(handler Handler) Handler {
-		return func(texts []string, toLang string) ([]string, error) {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 			var result []string
-			var err error
-
-			for i := 1; i <= retryCount; i++ {
-				result, err = handler(texts, toLang)
-				if err == nil {
-					return result, nil
-				}
+			err := RetryCall(ctx, retryCount, baseDelay, func() error {
+				var err error
+				result, err = handler(ctx, texts, fromLang, toLang)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			return result, nil
+		}
+	}
+}
 
-				if !isRetryable(err) {
-					return nil, err
-				}
+// RetryCall 按Retry的策略调用fn，用于不经过翻译处理链的请求，如语言检测
+func RetryCall(ctx context.Context, retryCount, baseDelay int, fn func() error) error {
+	var err error
+	for i := 1; i <= retryCount; i++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
 
-				time.Sleep(time.Duration(baseDelay*i) * time.Second)
-			}
+		if !isRetryable(err) {
+			return err
+		}
+		if i == retryCount { // 最后一次失败后无需再等待
+			break
+		}
 
-			return nil, errors.New("max retries exceeded")
+		if err := sleep(ctx, time.Duration(baseDelay*i)*time.Second); err != nil {
+			return err
 		}
 	}
+
+	return fmt.Errorf("%w: %w", transerrors.ErrMaxRetries, err)
+}
+
+// sleep 等待d时长，ctx取消时立即返回
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // isRetryable 判断错误是否可重试
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smilingpoplar/translate/translator/transerrors"
+)
+
+func TestRetry_CancelDuringBackoff(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	handler := Retry(3, 60)(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		calls++
+		cancel() // 第一次失败后取消，不应等待60秒的退避
+		return nil, transerrors.ErrTooManyRequests
+	})
+
+	start := time.Now()
+	_, err := handler(ctx, []string{"hello"}, "", "zh-CN")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retry did not abort promptly, took %v", elapsed)
+	}
+}
+
+func TestRetry_SucceedsAfterRetryableError(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	handler := Retry(3, 0)(func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+		calls++
+		if calls == 1 {
+			return nil, transerrors.ErrInvalidJSON
+		}
+		return texts, nil
+	})
+
+	got, err := handler(context.Background(), []string{"hello"}, "", "zh-CN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "hello" {
+		t.Errorf("expected [hello], got %v", got)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestRetryCall(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	err := RetryCall(context.Background(), 3, 0, func() error {
+		calls++
+		return transerrors.ErrTooManyRequests
+	})
+	if !errors.Is(err, transerrors.ErrMaxRetries) || !errors.Is(err, transerrors.ErrTooManyRequests) {
+		t.Fatalf("expected ErrMaxRetries wrapping ErrTooManyRequests, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+
+	calls = 0
+	permanent := errors.New("bad request")
+	if err := RetryCall(context.Background(), 3, 0, func() error {
+		calls++
+		return permanent
+	}); err != permanent || calls != 1 {
+		t.Errorf("expected permanent error after 1 call, got %v after %d calls", err, calls)
+	}
+}
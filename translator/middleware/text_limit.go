@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -9,7 +10,7 @@ import (
 func TextLimit(maxLen int) Middleware {
 	return func(handler Handler) Handler {
 		handler = TextsRegroup(maxLen)(handler)
-		return func(texts []string, toLang string) ([]string, error) {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 			texts, info, err := splitLongTexts([]string{strings.Join(texts, "\n")}, maxLen-len(texts)+1)
 			if err != nil || len(info.Mapping) > 1 {
 				return nil, fmt.Errorf("error split long text: %w", err)
@@ -17,7 +18,7 @@ func TextLimit(maxLen int) Middleware {
 			if len(info.Mapping) == 1 {
 				texts = texts[1:]
 			}
-			return handler(texts, toLang)
+			return handler(ctx, texts, fromLang, toLang)
 		}
 	}
 }
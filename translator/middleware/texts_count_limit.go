@@ -0,0 +1,25 @@
+package middleware
+
+import "context"
+
+// 防止单次请求的文本条数>maxCount，将texts按顺序分批依次翻译
+func TextsCountLimit(maxCount int) Middleware {
+	return func(handler Handler) Handler {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+			if maxCount <= 0 || len(texts) <= maxCount {
+				return handler(ctx, texts, fromLang, toLang)
+			}
+
+			result := make([]string, 0, len(texts))
+			for start := 0; start < len(texts); start += maxCount {
+				end := min(start+maxCount, len(texts))
+				translated, err := handler(ctx, texts[start:end], fromLang, toLang)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, translated...)
+			}
+			return result, nil
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -9,12 +10,13 @@ func TextsLimit(maxLen int) Middleware {
 	return func(handler Handler) Handler {
 		handler = TextsRegroup(maxLen)(handler)
 
-		return func(texts []string, toLang string) ([]string, error) {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 			texts, info, err := splitLongTexts(texts, maxLen)
 			if err != nil {
 				return nil, fmt.Errorf("error split long text: %w", err)
 			}
-			result, err := handler(texts, toLang)
+			ctx = withReorderBuffer(ctx, newReorderBuffer(len(texts), info))
+			result, err := handler(ctx, texts, fromLang, toLang)
 			if err != nil {
 				return nil, err
 			}
@@ -1,34 +1,48 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
 
 func TextsRegroup(maxLen int) Middleware {
 	return func(handler Handler) Handler {
-		return func(texts []string, toLang string) ([]string, error) {
+		return func(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 			groups, err := regroupTexts(texts, maxLen)
 			if err != nil {
 				return nil, fmt.Errorf("error group texts: %w", err)
 			}
 
+			// 任一分组出错时取消其他分组
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
 			results := make([][]string, len(groups))
-			errs := make([]error, len(groups))
+			var firstErr error
+			var once sync.Once
 			var wg sync.WaitGroup
+			offset := 0
 			for i, group := range groups {
 				wg.Add(1)
-				go func(index int, g []string) {
+				go func(index, offset int, g []string) {
 					defer wg.Done()
-					results[index], errs[index] = handler(g, toLang)
-				}(i, group)
+					res, err := handler(withGroupOffset(ctx, offset), g, fromLang, toLang)
+					if err != nil {
+						once.Do(func() {
+							firstErr = err
+							cancel()
+						})
+						return
+					}
+					results[index] = res
+				}(i, offset, group)
+				offset += len(group)
 			}
 			wg.Wait()
 
-			for _, err := range errs {
-				if err != nil {
-					return nil, err
-				}
+			if firstErr != nil {
+				return nil, firstErr
 			}
 
 			var finalResult []string
@@ -0,0 +1,370 @@
+// Ollama接口文档：
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/llm"
+	"github.com/smilingpoplar/translate/translator/middleware"
+	"github.com/smilingpoplar/translate/translator/transerrors"
+	"github.com/smilingpoplar/translate/util"
+)
+
+const (
+	BaseURL = "http://localhost:11434"
+
+	// 未配置num-ctx时，取模型上下文长度，但不超过该值以免占用过多显存
+	maxAutoNumCtx = 8192
+	// prompt模板等固定开销的token数
+	promptOverheadTokens = 512
+	// 按每token约2字节估算，中日韩文字偏保守
+	bytesPerToken = 2
+	// 分组字节数下限
+	minBatchSize = 500
+	// 本地模型加载和生成较慢
+	requestTimeout = 10 * time.Minute
+)
+
+// responseSchema 约束输出为config.Translation数组
+var responseSchema = map[string]any{
+	"type": "array",
+	"items": map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":   map[string]any{"type": "integer"},
+			"text": map[string]any{"type": "string"},
+		},
+		"required": []string{"id", "text"},
+	},
+}
+
+type Ollama struct {
+	client    *http.Client
+	model     string
+	numCtx    int
+	keepAlive any
+	format    any
+	glossary  util.Glossary
+	onTrans   func([]string) error
+	Name      string
+	baseURL   string
+	rpm       int
+	maxConc   int
+	extraBody map[string]any
+	cache     *util.Cache
+
+	mu      sync.Mutex
+	handler middleware.Handler // 首次翻译时根据上下文长度构造
+}
+
+type option func(*Ollama) error
+
+func New(sc *config.ServiceConfig, opts ...option) (*Ollama, error) {
+	service := sc.Name
+	baseURL := sc.GetEnvValue("base-url")
+	if baseURL == "" {
+		baseURL = BaseURL
+	}
+	numCtx := 0
+	if s := sc.GetEnvValue("num-ctx"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("error creating ollama translator: invalid num-ctx %q", s)
+		}
+		numCtx = n
+	}
+	format, err := parseFormat(sc.GetEnvValue("format"))
+	if err != nil {
+		return nil, fmt.Errorf("error creating ollama translator: %w", err)
+	}
+
+	o := &Ollama{
+		client:    &http.Client{},
+		model:     sc.GetEnvValue("model"),
+		numCtx:    numCtx,
+		keepAlive: parseKeepAlive(sc.GetEnvValue("keep-alive")),
+		format:    format,
+		Name:      service,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		rpm:       sc.GetRpmOrDefault(0), // 本地服务默认不限流
+		maxConc:   sc.GetMaxConcurrency(),
+		extraBody: sc.GetExtraBody(),
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("error creating ollama translator: %w", err)
+		}
+	}
+	if cache, err := util.NewCache(service, 20*time.Minute); err == nil {
+		o.cache = cache
+	} else {
+		return nil, fmt.Errorf("error creating ollama translator: %w", err)
+	}
+
+	return o, nil
+}
+
+func WithProxy(proxy string) option {
+	return func(o *Ollama) error {
+		return util.SetProxy(proxy, o.client)
+	}
+}
+
+func WithGlossary(glossary util.Glossary) option {
+	return func(o *Ollama) error {
+		o.glossary = glossary
+		return nil
+	}
+}
+
+// parseFormat 默认使用json schema约束输出，json表示只约束为json对象，none表示不约束
+func parseFormat(s string) (any, error) {
+	switch s {
+	case "", "schema":
+		return responseSchema, nil
+	case "json":
+		return "json", nil
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("invalid format %q, expect schema, json or none", s)
+}
+
+// parseKeepAlive 数字按秒传递，其余按时长字符串传递，比如5m
+func parseKeepAlive(s string) any {
+	if s == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return s
+}
+
+// batchSize 由上下文长度估算每组文本的字节数，输入和输出各占一半
+func batchSize(numCtx int) int {
+	return max((numCtx-promptOverheadTokens)/2*bytesPerToken, minBatchSize)
+}
+
+// prepare 确定上下文长度、预热模型，并构造中间件链
+func (o *Ollama) prepare(ctx context.Context) (middleware.Handler, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.handler != nil {
+		return o.handler, nil
+	}
+
+	if o.numCtx == 0 {
+		modelCtx, err := o.contextLength(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o.numCtx = min(modelCtx, maxAutoNumCtx)
+	}
+	if err := o.warmUp(ctx); err != nil {
+		return nil, err
+	}
+
+	chain := middleware.Chain(
+		middleware.TextsLimit(batchSize(o.numCtx)),
+		middleware.OnTranslated(&o.onTrans),
+		middleware.PassThrough(),
+		middleware.Glossary(o.glossary),
+		middleware.Retry(3, 1),
+		middleware.Cache(o.cache),
+		middleware.RateLimit(o.rpm),
+		middleware.Concurrent(o.maxConc),
+	)
+	o.handler = chain(o.translate)
+	return o.handler, nil
+}
+
+type showResponse struct {
+	ModelInfo map[string]any `json:"model_info"`
+}
+
+// contextLength 从/api/show的model_info中读取<架构>.context_length
+func (o *Ollama) contextLength(ctx context.Context) (int, error) {
+	var data showResponse
+	if err := o.doJSON(ctx, "/api/show", map[string]any{"model": o.model}, &data); err != nil {
+		return 0, fmt.Errorf("error showing model %s: %w", o.model, err)
+	}
+	for key, v := range data.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := v.(float64); ok && n > 0 {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("error showing model %s: no context_length in model_info", o.model)
+}
+
+// warmUp 发送不含消息的请求，让Ollama提前按num_ctx加载模型
+func (o *Ollama) warmUp(ctx context.Context) error {
+	request := chatRequest{
+		Model:     o.model,
+		Messages:  []message{},
+		KeepAlive: o.keepAlive,
+		Options:   map[string]any{"num_ctx": o.numCtx},
+	}
+	reqJSON, err := util.MergeJSON(request, o.extraBody)
+	if err != nil {
+		return err
+	}
+	if err := o.doJSON(ctx, "/api/chat", json.RawMessage(reqJSON), nil); err != nil {
+		return fmt.Errorf("error loading model %s: %w", o.model, err)
+	}
+	return nil
+}
+
+func (o *Ollama) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	prompt, err := config.GetPrompt(texts, fromLang, toLang)
+	if err != nil {
+		return nil, fmt.Errorf("error translating: %w", err)
+	}
+
+	result, err := o.sendRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := llm.ParseTranslations(unwrapArray(result), texts)
+	if err != nil {
+		return nil, fmt.Errorf("error translating: %w", err)
+	}
+	return parsed, nil
+}
+
+// unwrapArray format为json时模型只能输出对象，取出其中的数组，比如{"translations":[...]}
+func unwrapArray(str string) string {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(str), &obj); err != nil {
+		return str
+	}
+	for _, v := range obj {
+		if trimmed := bytes.TrimSpace(v); len(trimmed) > 0 && trimmed[0] == '[' {
+			return string(trimmed)
+		}
+	}
+	return str
+}
+
+func (o *Ollama) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return o.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (o *Ollama) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	handler, err := o.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, texts, fromLang, toLang)
+}
+
+func (o *Ollama) OnTranslated(f func([]string) error) {
+	o.onTrans = f
+}
+
+func (o *Ollama) Close() error {
+	return o.cache.Close()
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model     string         `json:"model"`
+	Messages  []message      `json:"messages"`
+	Stream    bool           `json:"stream"`
+	Format    any            `json:"format,omitempty"`
+	KeepAlive any            `json:"keep_alive,omitempty"`
+	Options   map[string]any `json:"options,omitempty"`
+}
+
+type chatResponse struct {
+	Message    message `json:"message"`
+	DoneReason string  `json:"done_reason"`
+}
+
+func (o *Ollama) sendRequest(ctx context.Context, prompt string) (string, error) {
+	request := chatRequest{
+		Model:     o.model,
+		Messages:  []message{{Role: "user", Content: prompt}},
+		Format:    o.format,
+		KeepAlive: o.keepAlive,
+		Options:   map[string]any{"num_ctx": o.numCtx},
+	}
+	reqJSON, err := util.MergeJSON(request, o.extraBody)
+	if err != nil {
+		return "", err
+	}
+	var response chatResponse
+	if err := o.doJSON(ctx, "/api/chat", json.RawMessage(reqJSON), &response); err != nil {
+		return "", err
+	}
+	if response.DoneReason == "length" {
+		return "", fmt.Errorf("error making request: %w by num-ctx %d", transerrors.ErrTruncated, o.numCtx)
+	}
+	return response.Message.Content, nil
+}
+
+func (o *Ollama) doJSON(ctx context.Context, path string, reqBody, respData any) error {
+	reqJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+path, bytes.NewReader(reqJSON))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return transerrors.ErrTooManyRequests
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			return fmt.Errorf("API error: %d - %s", resp.StatusCode, e.Error)
+		}
+		return fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	if respData == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, respData); err != nil {
+		return fmt.Errorf("error unmarshalling JSON: %w, resp body: %s", err, string(body))
+	}
+	return nil
+}
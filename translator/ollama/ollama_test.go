@@ -0,0 +1,164 @@
+package ollama
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/smilingpoplar/translate/config"
+)
+
+// fakeOllama 模拟Ollama的/api/show和/api/chat接口，译文为原文的大写
+type fakeOllama struct {
+	t             *testing.T
+	contextLength int
+	mu            sync.Mutex
+	warmUps       int
+	chats         int
+	numCtx        []float64
+	formats       []any
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/show":
+		json.NewEncoder(w).Encode(map[string]any{
+			"model_info": map[string]any{
+				"general.architecture": "llama",
+				"llama.context_length": f.contextLength,
+			},
+		})
+	case "/api/chat":
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			f.t.Error(err)
+			return
+		}
+		f.mu.Lock()
+		f.numCtx = append(f.numCtx, req.Options["num_ctx"].(float64))
+		if len(req.Messages) == 0 {
+			f.warmUps++
+			f.mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{"done": true, "done_reason": "load"})
+			return
+		}
+		f.chats++
+		f.formats = append(f.formats, req.Format)
+		f.mu.Unlock()
+
+		// 从prompt中取出json输入
+		prompt := req.Messages[0].Content
+		start := strings.Index(prompt, "[{")
+		end := strings.LastIndex(prompt, "}]") + 2
+		var trans []config.Translation
+		json.Unmarshal([]byte(prompt[start:end]), &trans)
+		for i := range trans {
+			trans[i].Text = strings.ToUpper(trans[i].Text)
+		}
+		content, _ := json.Marshal(trans)
+		if req.Format == "json" { // json模式只能输出对象
+			content, _ = json.Marshal(map[string]any{"translations": trans})
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":     message{Role: "assistant", Content: string(content)},
+			"done":        true,
+			"done_reason": "stop",
+		})
+	default:
+		f.t.Errorf("unexpected path %s", r.URL.Path)
+	}
+}
+
+// newTestOllama 创建指向stand-in服务的Ollama翻译器
+func newTestOllama(t *testing.T, fake *fakeOllama, env map[string]string) *Ollama {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	t.Setenv("TMPDIR", t.TempDir()) // 隔离缓存文件
+	t.Setenv("OLLAMA_BASE_URL", server.URL)
+	t.Setenv("OLLAMA_MODEL", "llama-test")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	o, err := New(config.NewServiceConfig("ollama"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func TestTranslate_ContextSizedBatches(t *testing.T) {
+	fake := &fakeOllama{t: t, contextLength: 1012}
+	o := newTestOllama(t, fake, nil)
+
+	// num_ctx 1012 => 每组500字节，3段300字节的文本需分3组
+	texts := []string{strings.Repeat("a", 300), strings.Repeat("b", 300), strings.Repeat("c", 300)}
+	got, err := o.Translate(texts, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range texts {
+		if got[i] != strings.ToUpper(texts[i]) {
+			t.Errorf("unexpected result %d: %q", i, got[i])
+		}
+	}
+	if _, err := o.Translate([]string{"hello"}, "", "zh-CN"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.warmUps != 1 {
+		t.Errorf("expected 1 warm-up, got %d", fake.warmUps)
+	}
+	if fake.chats != 4 {
+		t.Errorf("expected 4 chats, got %d", fake.chats)
+	}
+	for _, n := range fake.numCtx {
+		if n != 1012 {
+			t.Errorf("expected num_ctx 1012, got %v", n)
+		}
+	}
+}
+
+func TestTranslate_NumCtxAndJSONFormat(t *testing.T) {
+	fake := &fakeOllama{t: t, contextLength: 131072}
+	o := newTestOllama(t, fake, map[string]string{
+		"OLLAMA_NUM_CTX":    "4096",
+		"OLLAMA_FORMAT":     "json",
+		"OLLAMA_KEEP_ALIVE": "-1",
+	})
+	if o.keepAlive != -1 {
+		t.Errorf("expected keep_alive -1, got %v", o.keepAlive)
+	}
+
+	got, err := o.Translate([]string{"hello"}, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != "HELLO" {
+		t.Errorf("unexpected result %q", got[0])
+	}
+	if fake.numCtx[0] != 4096 || fake.formats[0] != "json" {
+		t.Errorf("unexpected num_ctx %v or format %v", fake.numCtx, fake.formats)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[int]int{
+		8192: 7680,
+		2048: 1536,
+		512:  minBatchSize,
+	}
+	for numCtx, want := range tests {
+		if got := batchSize(numCtx); got != want {
+			t.Errorf("batchSize(%d) = %d, want %d", numCtx, got, want)
+		}
+	}
+}
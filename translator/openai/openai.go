@@ -6,29 +6,42 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"maps"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	oai "github.com/sashabaranov/go-openai"
 	"github.com/smilingpoplar/translate/config"
+	"github.com/smilingpoplar/translate/translator/llm"
 	"github.com/smilingpoplar/translate/translator/middleware"
-	"github.com/smilingpoplar/translate/translator/transerrors"
 	"github.com/smilingpoplar/translate/util"
 )
 
+const (
+	// 单次请求的超时时间，LLM生成较慢
+	requestTimeout = 3 * time.Minute
+
+	kAzure = "azure"
+)
+
 type OpenAI struct {
 	config    *oai.ClientConfig
 	client    *oai.Client
 	model     string
 	prompt    string
 	handler   middleware.Handler
-	glossary  map[string]string
+	glossary  util.Glossary
 	onTrans   func([]string) error
 	Name      string
 	apiKey    string
 	extraBody map[string]any
 	cache     *util.Cache
+
+	// Azure OpenAI按部署名访问
+	azure      bool
+	deployment string
+	apiVersion string
 }
 
 type option func(*OpenAI) error
@@ -40,8 +53,21 @@ func New(sc *config.ServiceConfig, opts ...option) (*OpenAI, error) {
 	baseURL := sc.GetEnvValue("base-url")
 
 	o := &OpenAI{Name: service, model: model}
-	config := oai.DefaultConfig(key)
-	config.BaseURL = baseURL
+	var config oai.ClientConfig
+	if sc.Type == kAzure {
+		o.azure = true
+		o.deployment = sc.GetEnvValue("deployment")
+		o.apiVersion = sc.GetEnvValue("api-version")
+		if o.model == "" { // Azure按部署名路由，model可省略
+			o.model = o.deployment
+		}
+		config = oai.DefaultAzureConfig(key, baseURL)
+		config.APIVersion = o.apiVersion
+		config.AzureModelMapperFunc = func(string) string { return o.deployment }
+	} else {
+		config = oai.DefaultConfig(key)
+		config.BaseURL = baseURL
+	}
 	o.config = &config
 	o.client = oai.NewClientWithConfig(config)
 
@@ -62,6 +88,7 @@ func New(sc *config.ServiceConfig, opts ...option) (*OpenAI, error) {
 	chain := middleware.Chain(
 		middleware.TextsLimit(2000),
 		middleware.OnTranslated(&o.onTrans),
+		middleware.PassThrough(),
 		middleware.Glossary(o.glossary),
 		middleware.Retry(8, 3),
 		middleware.Cache(o.cache),
@@ -83,43 +110,42 @@ func WithProxy(proxy string) option {
 	}
 }
 
-func WithGlossary(glossary map[string]string) option {
+func WithGlossary(glossary util.Glossary) option {
 	return func(o *OpenAI) error {
 		o.glossary = glossary
 		return nil
 	}
 }
 
-func (o *OpenAI) translate(texts []string, toLang string) ([]string, error) {
+func (o *OpenAI) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
 	prompt := o.prompt
 	if prompt == "" {
 		var err error
-		prompt, err = config.GetPrompt(texts, toLang)
+		prompt, err = config.GetPrompt(texts, fromLang, toLang)
 		if err != nil {
 			return nil, fmt.Errorf("error translating: %w", err)
 		}
 	}
 
-	result, err := o.sendRequest(prompt)
+	result, err := o.sendRequest(ctx, prompt)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse response using the original texts count
-	parsed, err := parseResponse(result, len(texts))
+	parsed, err := llm.ParseTranslations(result, texts)
 	if err != nil {
 		return nil, fmt.Errorf("error translating: %w", err)
 	}
-	for i := range texts {
-		if texts[i] == parsed[i] && len(texts[i]) > 20 {
-			return nil, transerrors.ErrNoTranslation
-		}
-	}
 	return parsed, nil
 }
 
-func (o *OpenAI) Translate(texts []string, toLang string) ([]string, error) {
-	return o.handler(texts, toLang)
+func (o *OpenAI) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return o.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (o *OpenAI) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	return o.handler(ctx, texts, fromLang, toLang)
 }
 
 func (o *OpenAI) OnTranslated(f func([]string) error) {
@@ -130,7 +156,10 @@ func (o *OpenAI) Close() error {
 	return o.cache.Close()
 }
 
-func (o *OpenAI) sendRequest(prompt string) (string, error) {
+func (o *OpenAI) sendRequest(ctx context.Context, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
 	request := oai.ChatCompletionRequest{
 		Model: o.model,
 		Messages: []oai.ChatCompletionMessage{
@@ -143,7 +172,6 @@ func (o *OpenAI) sendRequest(prompt string) (string, error) {
 
 	// 如果没有额外参数，直接使用库方法
 	if len(o.extraBody) == 0 {
-		ctx := context.Background()
 		response, err := o.client.CreateChatCompletion(ctx, request)
 		if err != nil {
 			return "", fmt.Errorf("error making request: %w", err)
@@ -152,36 +180,28 @@ func (o *OpenAI) sendRequest(prompt string) (string, error) {
 	}
 
 	// 有额外参数，使用手动构造方式
-	return o.sendRequestWithExtra(request)
+	return o.sendRequestWithExtra(ctx, request)
 }
 
-func (o *OpenAI) sendRequestWithExtra(request oai.ChatCompletionRequest) (string, error) {
-	// 序列化request
-	reqJSON, err := json.Marshal(request)
-	if err != nil {
-		return "", err
-	}
-
-	// 合并额外参数extraBody
-	var reqMap map[string]any
-	if err := json.Unmarshal(reqJSON, &reqMap); err != nil {
-		return "", err
-	}
-	maps.Copy(reqMap, o.extraBody)
-	finalJSON, err := json.Marshal(reqMap)
+func (o *OpenAI) sendRequestWithExtra(ctx context.Context, request oai.ChatCompletionRequest) (string, error) {
+	// 序列化request，并合并额外参数extraBody
+	finalJSON, err := util.MergeJSON(request, o.extraBody)
 	if err != nil {
 		return "", err
 	}
 
 	// 构造并发送 HTTP 请求
-	ctx := context.Background()
 	req, err := http.NewRequestWithContext(ctx, "POST",
-		o.config.BaseURL+"/chat/completions", bytes.NewReader(finalJSON))
+		o.chatCompletionsURL(), bytes.NewReader(finalJSON))
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	if o.azure {
+		req.Header.Set(oai.AzureAPIKeyHeader, o.apiKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
 
 	resp, err := o.config.HTTPClient.Do(req)
 	if err != nil {
@@ -204,28 +224,13 @@ func (o *OpenAI) sendRequestWithExtra(request oai.ChatCompletionRequest) (string
 	return response.Choices[0].Message.Content, nil
 }
 
-type Translation struct {
-	ID   int    `json:"id"`
-	Text string `json:"text"`
-}
-
-func parseResponse(str string, expectedCount int) ([]string, error) {
-	trans := []Translation{}
-	if err := json.Unmarshal([]byte(str), &trans); err != nil {
-		return nil, fmt.Errorf("error parsing response: %w, response str: %s", transerrors.ErrInvalidJSON, str)
-	}
-
-	// 验证返回的段数是否与期望的段数匹配
-	if len(trans) != expectedCount {
-		return nil, fmt.Errorf("error parsing response: %w, expected %d, got %d", transerrors.ErrCountMismatch, expectedCount, len(trans))
-	}
-
-	arr := make([]string, len(trans))
-	for _, t := range trans {
-		if t.ID >= len(arr) {
-			return nil, fmt.Errorf("error parsing response: %w, invalid id %d", transerrors.ErrInvalidJSON, t.ID)
-		}
-		arr[t.ID] = t.Text
+// chatCompletionsURL 与go-openai库方法使用相同的地址
+func (o *OpenAI) chatCompletionsURL() string {
+	baseURL := strings.TrimRight(o.config.BaseURL, "/")
+	if !o.azure {
+		return baseURL + "/chat/completions"
 	}
-	return arr, nil
+	query := url.Values{"api-version": {o.apiVersion}}
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?%s",
+		baseURL, url.PathEscape(o.deployment), query.Encode())
 }
@@ -0,0 +1,76 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smilingpoplar/translate/config"
+)
+
+// newTestAzure 创建指向stand-in服务的Azure OpenAI翻译器，并校验请求地址和鉴权头
+func newTestAzure(t *testing.T, extraBody string) *OpenAI {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/gpt-deploy/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("api-version"); got != "2024-10-21" {
+			t.Errorf("unexpected api-version %q", got)
+		}
+		if r.Header.Get("api-key") != "test-key" || r.Header.Get("Authorization") != "" {
+			t.Errorf("unexpected auth headers %v", r.Header)
+		}
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if extraBody != "" && req["temperature"] != 0.5 {
+			t.Errorf("extra-body not merged: %v", req)
+		}
+
+		content := `[{"id":0,"text":"你好"}]`
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":%q}}]}`, content)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("TMPDIR", t.TempDir()) // 隔离缓存文件
+	t.Setenv("AZURE_BASE_URL", server.URL)
+	t.Setenv("AZURE_API_KEY", "test-key")
+	t.Setenv("AZURE_DEPLOYMENT", "gpt-deploy")
+	t.Setenv("AZURE_API_VERSION", "2024-10-21")
+	t.Setenv("AZURE_EXTRA_BODY", extraBody)
+
+	sc := config.NewServiceConfig("azure")
+	if err := sc.ValidateEnvArgs(); err != nil {
+		t.Fatal(err)
+	}
+	o, err := New(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func TestTranslate_Azure(t *testing.T) {
+	tests := []struct {
+		name      string
+		extraBody string
+	}{
+		{"client path", ""},
+		{"extra-body path", `{"temperature": 0.5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestAzure(t, tt.extraBody)
+			got, err := o.Translate([]string{"hello"}, "", "zh-CN")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0] != "你好" {
+				t.Errorf("unexpected result %q", got[0])
+			}
+		})
+	}
+}
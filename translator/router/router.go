@@ -0,0 +1,133 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/smilingpoplar/translate/translator/middleware"
+	"github.com/smilingpoplar/translate/util"
+)
+
+// 与LLM服务的分组大小一致
+const batchSize = 2000
+
+type Translator interface {
+	TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error)
+}
+
+// Route 目标语言属于To、且检测到的源语言属于From时，使用Translator翻译；条件为空表示不限
+type Route struct {
+	To         []string
+	From       []string
+	Name       string
+	Translator Translator
+}
+
+// Router 按语言规则为每条文本选择服务，按顺序取首个匹配的规则
+type Router struct {
+	routes     []Route
+	detectFrom bool // 有规则限定源语言时才检测
+	handler    middleware.Handler
+	onTrans    func([]string) error
+}
+
+func New(routes []Route) (*Router, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("error creating router translator: no routes")
+	}
+
+	r := &Router{routes: routes}
+	for _, route := range routes {
+		if len(route.From) > 0 {
+			r.detectFrom = true
+		}
+	}
+	chain := middleware.Chain(
+		middleware.TextsLimit(batchSize),
+		middleware.OnTranslated(&r.onTrans),
+	)
+	r.handler = chain(r.translate)
+	return r, nil
+}
+
+// match 返回首个匹配规则的下标，没有匹配时返回-1。未指定源语言时检测每条文本的语言
+func (r *Router) match(text, fromLang, toLang string) int {
+	if fromLang == "" && r.detectFrom {
+		fromLang, _ = util.DetectLang(text)
+	}
+	for i, route := range r.routes {
+		if len(route.To) > 0 && !util.MatchLang(toLang, route.To) {
+			continue
+		}
+		if len(route.From) > 0 && !util.MatchLang(fromLang, route.From) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func (r *Router) translate(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	// 按规则分组，组内保持原有顺序
+	groups := make(map[int][]int)
+	var order []int
+	for i, text := range texts {
+		idx := r.match(text, fromLang, toLang)
+		if idx < 0 {
+			return nil, fmt.Errorf("error translating: no route for target language %s", toLang)
+		}
+		if _, ok := groups[idx]; !ok {
+			order = append(order, idx)
+		}
+		groups[idx] = append(groups[idx], i)
+	}
+
+	result := make([]string, len(texts))
+	for _, idx := range order {
+		route := r.routes[idx]
+		indices := groups[idx]
+		group := make([]string, len(indices))
+		for j, i := range indices {
+			group[j] = texts[i]
+		}
+
+		translated, err := route.Translator.TranslateContext(ctx, group, fromLang, toLang)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", route.Name, err)
+		}
+		for j, i := range indices {
+			result[i] = translated[j]
+		}
+	}
+	return result, nil
+}
+
+func (r *Router) Translate(texts []string, fromLang, toLang string) ([]string, error) {
+	return r.TranslateContext(context.Background(), texts, fromLang, toLang)
+}
+
+func (r *Router) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	return r.handler(ctx, texts, fromLang, toLang)
+}
+
+func (r *Router) OnTranslated(fn func([]string) error) {
+	r.onTrans = fn
+}
+
+// Close 关闭各规则的服务，多条规则可能共用同一个服务
+func (r *Router) Close() error {
+	var errs []error
+	closed := make(map[Translator]bool)
+	for _, route := range r.routes {
+		if closed[route.Translator] {
+			continue
+		}
+		closed[route.Translator] = true
+		if c, ok := route.Translator.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeTranslator 返回带前缀的原文，并记录收到的文本
+type fakeTranslator struct {
+	prefix string
+	got    []string
+	closed int
+}
+
+func (f *fakeTranslator) TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error) {
+	f.got = append(f.got, texts...)
+	result := make([]string, len(texts))
+	for i, text := range texts {
+		result[i] = f.prefix + text
+	}
+	return result, nil
+}
+
+func (f *fakeTranslator) Close() error {
+	f.closed++
+	return nil
+}
+
+func TestRouter_ByTargetLang(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeTranslator{prefix: "llm:"}
+	mt := &fakeTranslator{prefix: "mt:"}
+	r, err := New([]Route{
+		{To: []string{"ja", "ko"}, Name: "llm", Translator: llm},
+		{Name: "mt", Translator: mt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Translate([]string{"hello"}, "", "ja")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != "llm:hello" {
+		t.Errorf("ja routed to %q, want llm", got[0])
+	}
+
+	got, err = r.Translate([]string{"hello"}, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != "mt:hello" {
+		t.Errorf("zh-CN routed to %q, want mt", got[0])
+	}
+}
+
+func TestRouter_BySourceLangKeepsOrder(t *testing.T) {
+	t.Parallel()
+
+	cjk := &fakeTranslator{prefix: "cjk:"}
+	other := &fakeTranslator{prefix: "other:"}
+	r, err := New([]Route{
+		{From: []string{"zh", "ja"}, Name: "cjk", Translator: cjk},
+		{Name: "other", Translator: other},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := []string{"你好", "hello", "こんにちは", "world"}
+	got, err := r.Translate(texts, "", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cjk:你好", "other:hello", "cjk:こんにちは", "other:world"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(cjk.got) != 2 || len(other.got) != 2 {
+		t.Errorf("unexpected groups %q, %q", cjk.got, other.got)
+	}
+}
+
+func TestRouter_FromLangOverridesDetection(t *testing.T) {
+	t.Parallel()
+
+	cjk := &fakeTranslator{prefix: "cjk:"}
+	other := &fakeTranslator{prefix: "other:"}
+	r, err := New([]Route{
+		{From: []string{"ja"}, Name: "cjk", Translator: cjk},
+		{Name: "other", Translator: other},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 汉字会被检测为中文，指定源语言后按日文路由
+	got, err := r.Translate([]string{"東京"}, "ja", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != "cjk:東京" {
+		t.Errorf("got %q, want routed to cjk", got[0])
+	}
+}
+
+func TestRouter_NoRoute(t *testing.T) {
+	t.Parallel()
+
+	r, err := New([]Route{{To: []string{"ja"}, Name: "llm", Translator: &fakeTranslator{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Translate([]string{"hello"}, "", "de"); err == nil {
+		t.Error("expected error for unmatched target language")
+	}
+}
+
+func TestRouter_CloseSharedOnce(t *testing.T) {
+	t.Parallel()
+
+	shared := &fakeTranslator{}
+	r, err := New([]Route{
+		{To: []string{"ja"}, Name: "shared", Translator: shared},
+		{To: []string{"ko"}, Name: "shared", Translator: shared},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if shared.closed != 1 {
+		t.Errorf("closed %d times, want 1", shared.closed)
+	}
+}
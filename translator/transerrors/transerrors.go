@@ -6,3 +6,6 @@ var ErrInvalidJSON = errors.New("invalid json response")
 var ErrTooManyRequests = errors.New("too many requests")
 var ErrCountMismatch = errors.New("translation count mismatch")
 var ErrNoTranslation = errors.New("no translation")
+var ErrContentBlocked = errors.New("content blocked")
+var ErrTruncated = errors.New("response truncated")
+var ErrMaxRetries = errors.New("max retries exceeded")
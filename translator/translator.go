@@ -1,7 +1,10 @@
 package translator
 
+import "context"
+
 type Translator interface {
-	Translate(texts []string, toLang string) ([]string, error)
+	Translate(texts []string, fromLang, toLang string) ([]string, error)
+	TranslateContext(ctx context.Context, texts []string, fromLang, toLang string) ([]string, error)
 }
 
 type TranslationObserver interface {
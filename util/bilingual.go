@@ -0,0 +1,152 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 双语输出的格式
+const (
+	BilingualInterleave = "interleave"   // 原文一行，接着译文一行
+	BilingualSideBySide = "side-by-side" // 原文和译文左右两栏
+	BilingualTSV        = "tsv"          // 原文和译文用制表符分隔
+)
+
+var BilingualModes = []string{BilingualInterleave, BilingualSideBySide, BilingualTSV}
+
+// 两栏输出时默认的总宽度
+const defaultLineWidth = 120
+
+// BilingualWriter 按原文顺序逐批写出原文和译文
+type BilingualWriter struct {
+	w         io.Writer
+	mode      string
+	originals []string
+	next      int // 下一条译文对应的原文下标
+	width     int // 两栏时每栏的显示宽度
+}
+
+// NewBilingualWriter lineWidth为两栏输出时的总宽度，不大于0时用默认值
+func NewBilingualWriter(w io.Writer, mode string, originals []string, lineWidth int) (*BilingualWriter, error) {
+	switch mode {
+	case BilingualInterleave, BilingualSideBySide, BilingualTSV:
+	default:
+		return nil, fmt.Errorf("unsupported bilingual mode %q, expect one of: %s", mode, strings.Join(BilingualModes, ", "))
+	}
+	if lineWidth <= 0 {
+		lineWidth = defaultLineWidth
+	}
+
+	// 左栏按最长的原文，但不超过一半宽度
+	width := 0
+	for _, s := range originals {
+		width = max(width, StringWidth(s))
+	}
+	width = max(min(width, (lineWidth-3)/2), 10)
+	return &BilingualWriter{w: w, mode: mode, originals: originals, width: width}, nil
+}
+
+// WriteLines 写出一批译文及对应的原文
+func (b *BilingualWriter) WriteLines(translated []string) error {
+	writer := bufio.NewWriter(b.w)
+	for _, t := range translated {
+		original := ""
+		if b.next < len(b.originals) {
+			original = b.originals[b.next]
+		}
+		b.next++
+
+		switch b.mode {
+		case BilingualInterleave:
+			if original == "" && t == "" { // 空行只输出一次
+				writer.WriteString("\n")
+				continue
+			}
+			writer.WriteString(original + "\n" + t + "\n")
+		case BilingualTSV:
+			writer.WriteString(tsvField(original) + "\t" + tsvField(t) + "\n")
+		case BilingualSideBySide:
+			left, right := wrapText(original, b.width), wrapText(t, b.width)
+			for i := 0; i < max(len(left), len(right)); i++ {
+				l, r := "", ""
+				if i < len(left) {
+					l = left[i]
+				}
+				if i < len(right) {
+					r = right[i]
+				}
+				line := l + strings.Repeat(" ", b.width-StringWidth(l)) + " | " + r
+				writer.WriteString(strings.TrimRight(line, " ") + "\n")
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error writing line: %w", err)
+	}
+	return nil
+}
+
+// tsvField 字段中的制表符换成空格，避免错列
+func tsvField(s string) string {
+	return strings.ReplaceAll(s, "\t", " ")
+}
+
+// wrapText 按显示宽度折行，尽量在空格处断开
+func wrapText(s string, width int) []string {
+	var lines []string
+	for StringWidth(s) > width {
+		w, cut, space := 0, 0, -1
+		for i, r := range s {
+			rw := runeWidth(r)
+			if w+rw > width {
+				break
+			}
+			w += rw
+			cut = i + utf8.RuneLen(r)
+			if r == ' ' {
+				space = i
+			}
+		}
+		if space > 0 {
+			cut = space
+		}
+		if cut == 0 { // 宽度容不下一个字符
+			_, cut = utf8.DecodeRuneInString(s)
+		}
+		lines = append(lines, strings.TrimRight(s[:cut], " "))
+		s = strings.TrimLeft(s[cut:], " ")
+	}
+	return append(lines, s)
+}
+
+// StringWidth 字符串在终端中的显示宽度，中日韩等宽字符占两列
+func StringWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == '\u200b':
+		return 0
+	case r >= 0x1100 && r <= 0x115F, // 谚文字母
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F, // 中日韩部首、符号、汉字、彝文
+		r >= 0xAC00 && r <= 0xD7A3,                // 谚文音节
+		r >= 0xF900 && r <= 0xFAFF,                // 兼容汉字
+		r >= 0xFE30 && r <= 0xFE4F,                // 兼容形式
+		r >= 0xFF00 && r <= 0xFF60,                // 全角字符
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F, // emoji
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD: // 扩展汉字
+		return 2
+	}
+	return 1
+}
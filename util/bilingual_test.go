@@ -0,0 +1,50 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBilingualWriter(t *testing.T) {
+	originals := []string{"Hello world", "", "Good\tbye"}
+	translated := []string{"你好世界", "", "再见"}
+	tests := []struct {
+		mode  string
+		width int
+		want  string
+	}{
+		{BilingualInterleave, 0, "Hello world\n你好世界\n\nGood\tbye\n再见\n"},
+		{BilingualTSV, 0, "Hello world\t你好世界\n\t\nGood bye\t再见\n"},
+		{BilingualSideBySide, 0, "Hello world | 你好世界\n            |\nGood\tbye    | 再见\n"},
+		{BilingualSideBySide, 23, "Hello      | 你好世界\nworld      |\n           |\nGood\tbye   | 再见\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, err := NewBilingualWriter(&buf, tt.mode, originals, tt.width)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 分两批写出，与流式输出一致
+		if err := w.WriteLines(translated[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteLines(translated[1:]); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("mode %s width %d:\ngot  %q\nwant %q", tt.mode, tt.width, got, tt.want)
+		}
+	}
+
+	if _, err := NewBilingualWriter(new(bytes.Buffer), "columns", originals, 0); err == nil {
+		t.Error("expect error for unsupported mode")
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	for s, want := range map[string]int{"abc": 3, "你好": 4, "한국어": 6, "ｶﾀｶﾅ": 4, "é": 1} {
+		if got := StringWidth(s); got != want {
+			t.Errorf("StringWidth(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
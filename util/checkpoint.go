@@ -0,0 +1,64 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint 分章节翻译的进度，中断后重新运行时跳过已完成的章节
+type Checkpoint struct {
+	path     string
+	Key      string     `json:"key"`      // 原文和翻译选项的哈希，变化后进度作废
+	Chapters [][]string `json:"chapters"` // 已完成章节的译文
+}
+
+// LoadCheckpoint 读取path中的进度，文件不存在或key不同时从头开始
+func LoadCheckpoint(path, key string) (*Checkpoint, error) {
+	c := &Checkpoint{path: path, Key: key}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+	var saved Checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Key != key {
+		return c, nil
+	}
+	c.Chapters = saved.Chapters
+	return c, nil
+}
+
+// Save 记录一章的译文，先写临时文件再改名，中断时不会留下不完整的进度
+func (c *Checkpoint) Save(translated []string) error {
+	c.Chapters = append(c.Chapters, translated)
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Remove 全部完成后删除进度文件
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.epub.progress")
+	c, err := LoadCheckpoint(path, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Chapters) != 0 {
+		t.Fatalf("new checkpoint has chapters: %q", c.Chapters)
+	}
+	if err := c.Save([]string{"一", "二"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save([]string{"三"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = LoadCheckpoint(path, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Chapters) != 2 || !slices.Equal(c.Chapters[1], []string{"三"}) {
+		t.Errorf("loaded chapters = %q", c.Chapters)
+	}
+
+	// 原文或选项变化后从头开始
+	if c, _ = LoadCheckpoint(path, "k2"); len(c.Chapters) != 0 {
+		t.Errorf("checkpoint with other key has chapters: %q", c.Chapters)
+	}
+
+	if err := c.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ = LoadCheckpoint(path, "k1"); len(c.Chapters) != 0 {
+		t.Errorf("removed checkpoint has chapters: %q", c.Chapters)
+	}
+}
@@ -1,49 +1,255 @@
 package util
 
 import (
+	"encoding/csv"
+	"encoding/xml"
 	"fmt"
+	"maps"
 	"os"
-	"regexp"
-
-	"github.com/gocarina/gocsv"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
 )
 
+// Glossary 术语表：术语原文 => 译文和匹配方式
+type Glossary map[string]GlossaryEntry
+
+// GlossaryEntry 术语在各目标语言下的译文，以及在原文中的匹配方式。
+// 默认整词匹配、不区分大小写
 type GlossaryEntry struct {
-	From string `csv:"from"`
-	To   string `csv:"to"`
+	Targets       map[string]string // 目标语言 => 译文，两列的 from,to 术语表不区分目标语言，记在语言""下
+	Langs         []string          // Targets中的语言按CSV列、TBX中的先后顺序，同样匹配时取靠前的
+	CaseSensitive bool              // case：区分大小写
+	Substring     bool              // substring：也匹配词的一部分
+	Regex         bool              // regex：原文是正则表达式，译文可以用$1、${name}引用捕获组
+	Inflect       bool              // inflect：也匹配复数、过去式等英语词形变化
+}
+
+// NewGlossary 由不区分目标语言的 原文 => 译文 创建术语表
+func NewGlossary(terms map[string]string) Glossary {
+	g := make(Glossary, len(terms))
+	for from, to := range terms {
+		g[from] = GlossaryEntry{Targets: map[string]string{"": to}}
+	}
+	return g
+}
+
+// Translation 术语在目标语言toLang下的译文。有该语言的列但译文为空时返回""、true，表示术语保持原文不翻译；
+// 没有该语言的列时返回false，术语不受术语表约束，交给翻译服务正常翻译。
+// 优先使用完全相同的语言列，其次是同一语言的其他地区（如zh列用于zh-CN），最后是不区分语言的译文；
+// 多列同样匹配时（如pt-BR、pt-PT列用于pt）取术语表中靠前的列
+func (g Glossary) Translation(term, toLang string) (string, bool) {
+	entry := g[term]
+	targets, langs := entry.Targets, entry.langs()
+	toLang = strings.ReplaceAll(toLang, "_", "-")
+	base, region, _ := strings.Cut(toLang, "-")
+	found := false
+	for _, match := range []func(lang string) bool{
+		func(lang string) bool { return strings.EqualFold(lang, toLang) },
+		func(lang string) bool { return strings.EqualFold(lang, base) },
+		func(lang string) bool { // 目标语言不带地区时，使用该语言任一地区的列（如ja-JP列用于ja）
+			langBase, _, _ := strings.Cut(lang, "-")
+			return region == "" && strings.EqualFold(langBase, base)
+		},
+	} {
+		for _, lang := range langs {
+			if lang != "" && match(strings.ReplaceAll(lang, "_", "-")) {
+				if to := targets[lang]; to != "" {
+					return to, true
+				}
+				found = true // 空的列，继续找同一语言其他列的译文
+			}
+		}
+	}
+	if to, ok := targets[""]; ok {
+		return to, true
+	}
+	return "", found
 }
 
-func LoadGlossary(filepath string) (map[string]string, error) {
-	if filepath == "" {
+// langs 按先后顺序返回有译文的语言，没有记录顺序时按语言代码排序，保证结果稳定
+func (e GlossaryEntry) langs() []string {
+	if len(e.Langs) > 0 {
+		return e.Langs
+	}
+	return slices.Sorted(maps.Keys(e.Targets))
+}
+
+// Terms 目标语言toLang下的 原文 => 译文，译文为""的术语保持原文，没有该语言列的术语不在其中
+func (g Glossary) Terms(toLang string) map[string]string {
+	m := make(map[string]string, len(g))
+	for from := range g {
+		if to, ok := g.Translation(from, toLang); ok {
+			m[from] = to
+		}
+	}
+	return m
+}
+
+// LoadGlossary 加载术语表，支持CSV和TBX（.tbx）文件。
+// CSV可以是无表头的 from,to[,flags]，也可以是以source开头的表头指定各目标语言列，如 source,zh-CN,ja,fr,flags。
+// flags为空格或|分隔的匹配方式：case、substring、regex、inflect
+func LoadGlossary(path string) (Glossary, error) {
+	if path == "" {
 		return nil, nil
 	}
 
-	file, err := os.Open(filepath)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("error reading csv file: %v", err)
+		return nil, fmt.Errorf("error reading glossary file: %v", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".tbx") {
+		return parseTBX(data)
 	}
-	defer file.Close()
+	return parseGlossaryCSV(data)
+}
 
-	var entries []GlossaryEntry
-	if err := gocsv.UnmarshalWithoutHeaders(file, &entries); err != nil {
-		return nil, fmt.Errorf("error unmarshalling csv: %v", err)
+func parseGlossaryCSV(data []byte) (Glossary, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(data), "\ufeff")))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing csv: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("error parsing csv: empty glossary")
 	}
 
-	m := make(map[string]string, len(entries))
-	for _, entry := range entries {
-		if entry.From != "" {
-			m[entry.From] = entry.To
+	columns := []string{"", "flags"} // 无表头时第二列是不区分语言的译文，第三列是flags
+	if strings.EqualFold(strings.TrimSpace(records[0][0]), "source") {
+		columns = records[0][1:]
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
 		}
+		records = records[1:]
 	}
-	return m, nil
+
+	g := make(Glossary, len(records))
+	for _, record := range records {
+		from := record[0]
+		if from == "" {
+			continue
+		}
+		entry := GlossaryEntry{Targets: make(map[string]string, len(columns))}
+		for i, column := range columns {
+			var value string
+			if i+1 < len(record) {
+				value = record[i+1]
+			}
+			if strings.EqualFold(column, "flags") {
+				if err := entry.setFlags(value); err != nil {
+					return nil, fmt.Errorf("error parsing glossary term %q: %v", from, err)
+				}
+				continue
+			}
+			entry.Targets[column] = value
+			entry.Langs = append(entry.Langs, column)
+		}
+		if _, err := NewTermMatcher(from, entry); err != nil {
+			return nil, fmt.Errorf("error parsing glossary term %q: %v", from, err)
+		}
+		g[from] = entry
+	}
+	return g, nil
 }
 
-func GeneratePlaceholder(id int) string {
-	return fmt.Sprintf("{ID_%d}", id)
+// setFlags 解析空格或|分隔的匹配方式
+func (e *GlossaryEntry) setFlags(flags string) error {
+	for _, flag := range strings.FieldsFunc(flags, func(r rune) bool { return r == '|' || unicode.IsSpace(r) }) {
+		switch strings.ToLower(flag) {
+		case "case":
+			e.CaseSensitive = true
+		case "substring":
+			e.Substring = true
+		case "regex":
+			e.Regex = true
+		case "inflect":
+			e.Inflect = true
+		default:
+			return fmt.Errorf("unknown flag %q", flag)
+		}
+	}
+	return nil
+}
+
+// TBX术语库，兼容TBX 2（martif/termEntry/langSet）和TBX 3（tbx/conceptEntry/langSec）
+type tbxDocument struct {
+	Lang     string     `xml:"lang,attr"`
+	Entries  []tbxEntry `xml:"text>body>termEntry"`
+	Concepts []tbxEntry `xml:"text>body>conceptEntry"`
+}
+
+type tbxEntry struct {
+	LangSets []tbxLangSet `xml:"langSet"`
+	LangSecs []tbxLangSet `xml:"langSec"`
+}
+
+type tbxLangSet struct {
+	Lang     string   `xml:"lang,attr"`
+	Tigs     []string `xml:"tig>term"`
+	Ntigs    []string `xml:"ntig>termGrp>term"`
+	TermSecs []string `xml:"termSec>term"`
+}
+
+// term 语言下的首选术语
+func (ls tbxLangSet) term() string {
+	for _, terms := range [][]string{ls.Tigs, ls.Ntigs, ls.TermSecs} {
+		for _, term := range terms {
+			if term = strings.TrimSpace(term); term != "" {
+				return term
+			}
+		}
+	}
+	return ""
 }
 
-func BuildWordBoundaryRegex(word string) (*regexp.Regexp, error) {
-	escaped := regexp.QuoteMeta(word)
-	pattern := `(?i)\b` + escaped + `\b`
-	return regexp.Compile(pattern)
+// parseTBX 以根元素xml:lang为源语言，未指定时以每个条目的第一种语言为源语言
+func parseTBX(data []byte) (Glossary, error) {
+	var doc tbxDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing tbx: %v", err)
+	}
+
+	g := make(Glossary)
+	for _, entry := range append(doc.Entries, doc.Concepts...) {
+		langSets := append(entry.LangSets, entry.LangSecs...)
+		if len(langSets) == 0 {
+			continue
+		}
+		source := 0
+		if doc.Lang != "" {
+			source = -1
+			for i, ls := range langSets {
+				if strings.EqualFold(ls.Lang, doc.Lang) {
+					source = i
+					break
+				}
+			}
+			if source < 0 {
+				continue
+			}
+		}
+
+		from := langSets[source].term()
+		if from == "" {
+			continue
+		}
+		entry := GlossaryEntry{Targets: make(map[string]string, len(langSets)-1)}
+		for i, ls := range langSets {
+			if i != source && ls.Lang != "" {
+				entry.Targets[ls.Lang] = ls.term()
+				entry.Langs = append(entry.Langs, ls.Lang)
+			}
+		}
+		g[from] = entry
+	}
+	if len(g) == 0 {
+		return nil, fmt.Errorf("error parsing tbx: no term entries")
+	}
+	return g, nil
+}
+
+func GeneratePlaceholder(id int) string {
+	return fmt.Sprintf("{ID_%d}", id)
 }
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TermMatcher 按术语表条目的匹配方式在文本中查找术语
+type TermMatcher struct {
+	regex     *regexp.Regexp
+	wholeWord bool // 匹配的两端不能紧接着其他字母数字
+	expand    bool // 译文中引用正则的捕获组
+}
+
+// NewTermMatcher 创建术语term的匹配器。
+// 整词匹配在运行时检查匹配两端的字符，而不用只认ASCII的\b，这样中日韩术语也能匹配
+func NewTermMatcher(term string, entry GlossaryEntry) (*TermMatcher, error) {
+	pattern := term
+	if !entry.Regex {
+		variants := []string{term}
+		if entry.Inflect {
+			variants = inflections(term)
+		}
+		for i, v := range variants {
+			variants[i] = regexp.QuoteMeta(v)
+		}
+		pattern = strings.Join(variants, "|")
+	}
+	if !entry.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &TermMatcher{regex: regex, wholeWord: !entry.Substring, expand: entry.Regex}, nil
+}
+
+// FindAll 返回所有匹配的位置，格式同regexp.FindAllStringSubmatchIndex
+func (m *TermMatcher) FindAll(text string) [][]int {
+	locs := m.regex.FindAllStringSubmatchIndex(text, -1)
+	if !m.wholeWord {
+		return locs
+	}
+	result := locs[:0]
+	for _, loc := range locs {
+		if loc[0] < loc[1] && isWordBoundary(text, loc[0], loc[1]) {
+			result = append(result, loc)
+		}
+	}
+	return result
+}
+
+// MatchString 文本中是否含有术语
+func (m *TermMatcher) MatchString(text string) bool {
+	return len(m.FindAll(text)) > 0
+}
+
+// Expand 匹配loc对应的译文，正则术语展开译文中的捕获组引用
+func (m *TermMatcher) Expand(template, text string, loc []int) string {
+	if !m.expand {
+		return template
+	}
+	return string(m.regex.ExpandString(nil, template, text, loc))
+}
+
+// isWordBoundary text[start:end]两端是否是词边界：
+// 匹配的首尾是字母数字时，外侧不能紧接着字母数字。中日韩文字不用空格分词，不算字母
+func isWordBoundary(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:])
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(first) && isWordRune(before) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text[:end])
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(last) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+		return false
+	}
+	return !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai)
+}
+
+// inflections 英语术语末尾单词的常见词形变化，按长度降序排列以优先匹配长的
+func inflections(term string) []string {
+	last, _ := utf8.DecodeLastRuneInString(term)
+	if last > unicode.MaxASCII || !unicode.IsLetter(last) {
+		return []string{term}
+	}
+
+	variants := []string{term, term + "'s"}
+	lower := strings.ToLower(term)
+	stem := term[:len(term)-1]
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		variants = append(variants, stem+"ies", stem+"ied", term+"ing")
+	case strings.HasSuffix(lower, "e"):
+		variants = append(variants, term+"s", term+"d", stem+"ing")
+	case strings.HasSuffix(lower, "s") || strings.HasSuffix(lower, "x") || strings.HasSuffix(lower, "z") ||
+		strings.HasSuffix(lower, "ch") || strings.HasSuffix(lower, "sh"):
+		variants = append(variants, term+"es", term+"ed", term+"ing")
+	default:
+		variants = append(variants, term+"s", term+"ed", term+"ing")
+	}
+	sort.SliceStable(variants, func(i, j int) bool { return len(variants[i]) > len(variants[j]) })
+	return variants
+}
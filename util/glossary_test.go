@@ -38,7 +38,7 @@ AI模型,AI模型
 	}
 
 	for from, expectedTo := range expectedTerms {
-		if to, ok := glossary[from]; !ok {
+		if to, ok := glossary[from].Targets[""]; !ok {
 			t.Errorf("term %q not found in glossary", from)
 		} else if to != expectedTo {
 			t.Errorf("term %q: expected to %q, got %q", from, expectedTo, to)
@@ -110,7 +110,7 @@ Kubernetes
 	}
 
 	// 验证 from 存在，但 to 为空字符串
-	if to, ok := glossary["AWS"]; !ok {
+	if to, ok := glossary["AWS"].Targets[""]; !ok {
 		t.Error("AWS should be in glossary")
 	} else if to != "" {
 		t.Errorf("AWS to should be empty, got %q", to)
@@ -175,10 +175,10 @@ func TestLoadGlossary_UTF8Encoding(t *testing.T) {
 	}
 
 	// 验证 UTF-8 字符被正确处理
-	if glossary["人工智能"] != "人工智能" {
+	if glossary["人工智能"].Targets[""] != "人工智能" {
 		t.Error("UTF-8 Chinese characters not handled correctly")
 	}
-	if glossary["😀"] != "😀" {
+	if glossary["😀"].Targets[""] != "😀" {
 		t.Error("Emoji not handled correctly")
 	}
 }
@@ -205,24 +205,20 @@ func TestGeneratePlaceholder(t *testing.T) {
 	}
 }
 
-// TestBuildWordBoundaryRegex 测试单词边界正则表达式
-// 注意：\b 只对 ASCII 字母数字字符 [a-zA-Z0-9_] 起作用
-// 对于中文、日文、特殊字符（如 C++ 中的 +）等，\b 不会正确匹配边界
-// 这是正则表达式的固有限制，建议用户：
-// 1. 对于 ASCII 术语：使用单词边界，效果最好
-// 2. 对于中文术语：仍然可以使用，但可能匹配包含该术语的更长文本
-// 3. 对于特殊字符术语：确保术语在上下文中是唯一的
-func TestBuildWordBoundaryRegex(t *testing.T) {
+// TestNewTermMatcher 测试术语的各种匹配方式
+// 整词匹配检查匹配两端的字符，中日韩文字不算字母，所以中文术语、紧挨中文的英文术语都能匹配
+func TestNewTermMatcher(t *testing.T) {
 	tests := []struct {
 		name     string
 		word     string
+		entry    GlossaryEntry
 		match    []string
 		notMatch []string
 	}{
 		{
 			name:  "simple word",
 			word:  "API",
-			match: []string{"API", "API is great", "Use API", "API.", "(API)"},
+			match: []string{"API", "API is great", "Use API", "API.", "(API)", "api", "调用API接口"},
 			notMatch: []string{
 				"APIS", "APIs", "nAPI",
 				"clipboard", "swagger",
@@ -238,46 +234,354 @@ func TestBuildWordBoundaryRegex(t *testing.T) {
 				"AWS33", "AWS3d",
 			},
 		},
+		{
+			name:     "special characters",
+			word:     "C++",
+			match:    []string{"C++", "C++ is fast", "use C++."},
+			notMatch: []string{"C+", "ObjC++"},
+		},
+		{
+			name:     "cjk",
+			word:     "人工智能",
+			match:    []string{"人工智能", "使用人工智能技术"},
+			notMatch: []string{"人工"},
+		},
+		{
+			name:     "non-ascii letters",
+			word:     "café",
+			match:    []string{"café", "Café au lait"},
+			notMatch: []string{"cafés"},
+		},
+		{
+			name:     "case sensitive",
+			word:     "Go",
+			entry:    GlossaryEntry{CaseSensitive: true},
+			match:    []string{"written in Go", "Go!"},
+			notMatch: []string{"go home", "GO", "Gopher"},
+		},
+		{
+			name:     "substring",
+			word:     "Kube",
+			entry:    GlossaryEntry{Substring: true},
+			match:    []string{"Kubernetes", "kubectl", "Kube"},
+			notMatch: []string{"Kub"},
+		},
+		{
+			name:     "regex",
+			word:     `v(\d+)\.(\d+)`,
+			entry:    GlossaryEntry{Regex: true},
+			match:    []string{"release v1.2", "V10.0"},
+			notMatch: []string{"v1", "abcv1.2"},
+		},
+		{
+			name:     "inflect",
+			word:     "proxy",
+			entry:    GlossaryEntry{Inflect: true},
+			match:    []string{"proxy", "proxies", "proxied", "proxying", "proxy's", "Proxies"},
+			notMatch: []string{"proxys", "proxyx"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			regex, err := BuildWordBoundaryRegex(tt.word)
+			matcher, err := NewTermMatcher(tt.word, tt.entry)
 			if err != nil {
-				t.Fatalf("BuildWordBoundaryRegex(%q) error: %v", tt.word, err)
+				t.Fatalf("NewTermMatcher(%q) error: %v", tt.word, err)
 			}
 
 			// 测试应该匹配的字符串
 			for _, text := range tt.match {
-				if !regex.MatchString(text) {
-					t.Errorf("regex should match %q, but it doesn't", text)
+				if !matcher.MatchString(text) {
+					t.Errorf("%q should match %q, but it doesn't", tt.word, text)
 				}
 			}
 
 			// 测试不应该匹配的字符串
 			for _, text := range tt.notMatch {
-				if regex.MatchString(text) {
-					t.Errorf("regex should not match %q, but it does", text)
+				if matcher.MatchString(text) {
+					t.Errorf("%q should not match %q, but it does", tt.word, text)
 				}
 			}
 		})
 	}
 }
 
-// TestBuildWordBoundaryRegex_SpecialCharacters 测试特殊字符转义
-func TestBuildWordBoundaryRegex_SpecialCharacters(t *testing.T) {
+// TestNewTermMatcher_SpecialCharacters 测试特殊字符转义
+func TestNewTermMatcher_SpecialCharacters(t *testing.T) {
 	// 包含正则表达式特殊字符的术语
 	specialWords := []string{
-		"C++",    // + 是重复字符
-		"C#",     // # 是注释字符（在某些正则引擎中）
-		".NET",   // . 是通配符
+		"C++",     // + 是重复字符
+		"C#",      // # 是注释字符（在某些正则引擎中）
+		".NET",    // . 是通配符
 		"AWS+SDK", // + 是重复字符
 	}
 
 	for _, word := range specialWords {
-		_, err := BuildWordBoundaryRegex(word)
+		_, err := NewTermMatcher(word, GlossaryEntry{})
 		if err != nil {
-			t.Errorf("BuildWordBoundaryRegex(%q) should not error, got: %v", word, err)
+			t.Errorf("NewTermMatcher(%q) should not error, got: %v", word, err)
+		}
+	}
+}
+
+// TestTermMatcher_Expand 测试正则术语的译文引用捕获组
+func TestTermMatcher_Expand(t *testing.T) {
+	matcher, err := NewTermMatcher(`(\w+)Service`, GlossaryEntry{Regex: true, CaseSensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "call UserService"
+	locs := matcher.FindAll(text)
+	if len(locs) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(locs))
+	}
+	if got := matcher.Expand("${1}服务", text, locs[0]); got != "User服务" {
+		t.Errorf("expected User服务, got %q", got)
+	}
+}
+
+// TestLoadGlossary_MultiLanguageCSV 测试带表头的多语言 CSV
+func TestLoadGlossary_MultiLanguageCSV(t *testing.T) {
+	tmpDir := t.TempDir()
+	glossaryFile := filepath.Join(tmpDir, "glossary.csv")
+
+	content := "\ufeffsource,zh-CN,ja,fr\n" +
+		"cloud,云,クラウド,nuage\n" +
+		"Kubernetes,,,\n" +
+		"pod,容器组,ポッド\n"
+	if err := os.WriteFile(glossaryFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	glossary, err := LoadGlossary(glossaryFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(glossary) != 3 {
+		t.Errorf("expected 3 terms (header skipped), got %d", len(glossary))
+	}
+	tests := []struct {
+		term, toLang, expected string
+	}{
+		{"cloud", "zh-CN", "云"},
+		{"cloud", "ja", "クラウド"},
+		{"cloud", "fr", "nuage"},
+		{"cloud", "de", ""},      // 没有该语言的列
+		{"Kubernetes", "ja", ""}, // 译文为空
+		{"pod", "fr", ""},        // 缺少的列按空译文处理
+	}
+	for _, tt := range tests {
+		if got, _ := glossary.Translation(tt.term, tt.toLang); got != tt.expected {
+			t.Errorf("Translation(%q, %q) = %q, want %q", tt.term, tt.toLang, got, tt.expected)
+		}
+	}
+}
+
+// TestLoadGlossary_TBX 测试加载 TBX 术语库
+func TestLoadGlossary_TBX(t *testing.T) {
+	tmpDir := t.TempDir()
+	glossaryFile := filepath.Join(tmpDir, "glossary.tbx")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<martif type="TBX" xml:lang="en">
+  <text><body>
+    <termEntry id="1">
+      <langSet xml:lang="zh-CN"><tig><term>云</term></tig></langSet>
+      <langSet xml:lang="en"><tig><term>cloud</term></tig></langSet>
+      <langSet xml:lang="ja"><ntig><termGrp><term>クラウド</term></termGrp></ntig></langSet>
+    </termEntry>
+    <termEntry id="2">
+      <langSet xml:lang="en"><tig><term>Kubernetes</term></tig></langSet>
+    </termEntry>
+    <termEntry id="3">
+      <langSet xml:lang="fr"><tig><term>nuage</term></tig></langSet>
+    </termEntry>
+  </body></text>
+</martif>
+`
+	if err := os.WriteFile(glossaryFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	glossary, err := LoadGlossary(glossaryFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 缺少源语言的条目被跳过
+	if len(glossary) != 2 {
+		t.Errorf("expected 2 terms, got %d: %v", len(glossary), glossary)
+	}
+	if got, _ := glossary.Translation("cloud", "zh-CN"); got != "云" {
+		t.Errorf("expected 云, got %q", got)
+	}
+	if got, _ := glossary.Translation("cloud", "ja"); got != "クラウド" {
+		t.Errorf("expected クラウド, got %q", got)
+	}
+	if _, ok := glossary["Kubernetes"]; !ok {
+		t.Error("Kubernetes should be in glossary")
+	}
+}
+
+// TestLoadGlossary_TBX3 测试 TBX 3 的 conceptEntry 格式
+func TestLoadGlossary_TBX3(t *testing.T) {
+	tmpDir := t.TempDir()
+	glossaryFile := filepath.Join(tmpDir, "glossary.tbx")
+
+	content := `<tbx type="TBX-Basic" style="dca" xml:lang="en" xmlns="urn:iso:std:iso:30042:ed-2">
+  <text><body>
+    <conceptEntry id="c1">
+      <langSec xml:lang="en"><termSec><term>cloud</term></termSec></langSec>
+      <langSec xml:lang="de"><termSec><term>Cloud</term></termSec></langSec>
+    </conceptEntry>
+  </body></text>
+</tbx>
+`
+	if err := os.WriteFile(glossaryFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	glossary, err := LoadGlossary(glossaryFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := glossary.Translation("cloud", "de"); got != "Cloud" {
+		t.Errorf("expected Cloud, got %q", got)
+	}
+}
+
+// TestGlossary_TranslationLangMatching 测试按目标语言选择译文列
+func TestGlossary_TranslationLangMatching(t *testing.T) {
+	glossary := Glossary{
+		"cloud": {Targets: map[string]string{"zh": "云", "zh-TW": "雲", "ja-JP": "クラウド", "": "Cloud"}},
+	}
+	tests := map[string]string{
+		"zh-TW": "雲",     // 完全相同的语言
+		"zh_tw": "雲",     // 忽略大小写和下划线
+		"zh-CN": "云",     // 不带地区的语言列
+		"ja":    "クラウド",  // 目标语言不带地区，使用带地区的列
+		"fr":    "Cloud", // 不区分语言的译文
+	}
+	for toLang, expected := range tests {
+		if got, _ := glossary.Translation("cloud", toLang); got != expected {
+			t.Errorf("Translation(cloud, %q) = %q, want %q", toLang, got, expected)
+		}
+	}
+	if got, _ := glossary.Translation("unknown", "zh-CN"); got != "" {
+		t.Errorf("expected empty translation for unknown term, got %q", got)
+	}
+}
+
+// TestGlossary_TranslationColumnOrder 测试多列同样匹配时按术语表中的列顺序选择
+func TestGlossary_TranslationColumnOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	for content, expected := range map[string]string{
+		"source,pt-BR,pt-PT\ncloud,nuvem (BR),nuvem (PT)\n": "nuvem (BR)",
+		"source,pt-PT,pt-BR\ncloud,nuvem (PT),nuvem (BR)\n": "nuvem (PT)",
+	} {
+		glossaryFile := filepath.Join(tmpDir, "glossary.csv")
+		if err := os.WriteFile(glossaryFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		glossary, err := LoadGlossary(glossaryFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for range 20 { // map遍历顺序随机，多试几次
+			if got, _ := glossary.Translation("cloud", "pt"); got != expected {
+				t.Fatalf("Translation(cloud, pt) = %q, want %q", got, expected)
+			}
+		}
+	}
+}
+
+// TestGlossary_TranslationMissingColumn 测试没有目标语言列的术语不受约束，空的列才表示保持原文
+func TestGlossary_TranslationMissingColumn(t *testing.T) {
+	tmpDir := t.TempDir()
+	glossaryFile := filepath.Join(tmpDir, "glossary.csv")
+	content := "source,zh-CN,ja\ncloud,云,クラウド\nKubernetes,,\n"
+	if err := os.WriteFile(glossaryFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	glossary, err := LoadGlossary(glossaryFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, toLang := range []string{"zh-TW", "fr"} {
+		if got, ok := glossary.Translation("cloud", toLang); ok {
+			t.Errorf("Translation(cloud, %s) = %q, want no translation", toLang, got)
+		}
+	}
+	if got, ok := glossary.Translation("Kubernetes", "ja"); !ok || got != "" {
+		t.Errorf("Translation(Kubernetes, ja) = %q, %v, want empty translation", got, ok)
+	}
+	if terms := glossary.Terms("fr"); len(terms) != 0 {
+		t.Errorf("Terms(fr) = %v, want none", terms)
+	}
+}
+
+// TestLoadGlossary_Flags 测试术语的匹配方式标记
+func TestLoadGlossary_Flags(t *testing.T) {
+	tmpDir := t.TempDir()
+	glossaryFile := filepath.Join(tmpDir, "glossary.csv")
+
+	content := `source,zh-CN,flags
+Go,Go语言,case
+proxy,代理,inflect|substring
+v(\d+),第${1}版,regex
+AWS,亚马逊云,
+`
+	if err := os.WriteFile(glossaryFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	glossary, err := LoadGlossary(glossaryFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if e := glossary["Go"]; !e.CaseSensitive || e.Substring || e.Regex || e.Inflect {
+		t.Errorf("unexpected flags for Go: %+v", e)
+	}
+	if e := glossary["proxy"]; !e.Inflect || !e.Substring {
+		t.Errorf("unexpected flags for proxy: %+v", e)
+	}
+	if e := glossary[`v(\d+)`]; !e.Regex {
+		t.Errorf("unexpected flags for regex term: %+v", e)
+	}
+	if _, ok := glossary["AWS"].Targets["flags"]; ok {
+		t.Error("flags column should not be a target language")
+	}
+
+	// 两列术语表的第三列是flags
+	legacyFile := filepath.Join(tmpDir, "legacy.csv")
+	if err := os.WriteFile(legacyFile, []byte("Go,Go语言,case\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	glossary, err = LoadGlossary(legacyFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := glossary["Go"]; !e.CaseSensitive || e.Targets[""] != "Go语言" {
+		t.Errorf("unexpected entry for Go: %+v", e)
+	}
+}
+
+// TestLoadGlossary_InvalidFlags 测试未知标记和无效正则
+func TestLoadGlossary_InvalidFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"unknown.csv": "Go,Go语言,exact\n",
+		"regex.csv":   "v(\\d+,版本,regex\n",
+	} {
+		glossaryFile := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(glossaryFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		if _, err := LoadGlossary(glossaryFile); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
 		}
 	}
 }
@@ -38,6 +38,15 @@ func IsTerminal() bool {
 	return term.IsTerminal(int(os.Stdin.Fd()))
 }
 
+// TerminalWidth 标准输出为终端时返回其宽度，否则返回0
+func TerminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
 func ReplaceWithDict(texts []string, dict map[string]string) []string {
 	result := make([]string, len(texts))
 	for i, text := range texts {
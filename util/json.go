@@ -0,0 +1,21 @@
+package util
+
+import (
+	"encoding/json"
+	"maps"
+)
+
+// MergeJSON 序列化v，并将extra中的字段合并到顶层
+func MergeJSON(v any, extra map[string]any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	maps.Copy(m, extra)
+	return json.Marshal(m)
+}
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 按文字系统判断语言，拉丁字母等多语言共用的文字无法区分，返回""
+var scriptLangs = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Han, "zh"},
+}
+
+// DetectScriptLang 离线检测文本语言，返回语言代码和置信度(0~1)
+func DetectScriptLang(text string) (string, float64) {
+	counts := make(map[string]int)
+	kana, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			kana++
+			continue
+		}
+		for _, s := range scriptLangs {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return "", 0
+	}
+
+	// 日文混用汉字和假名，有假名即视为日文
+	if kana > 0 {
+		return "ja", float64(kana+counts["zh"]) / float64(letters)
+	}
+
+	best, bestCount := "", 0
+	for _, s := range scriptLangs {
+		if c := counts[s.lang]; c > bestCount {
+			best, bestCount = s.lang, c
+		}
+	}
+	// 不足一半字母属于该文字时，不下结论
+	if bestCount*2 < letters {
+		return "", 0
+	}
+	return best, float64(bestCount) / float64(letters)
+}
+
+// Detection 检测到的语言和置信度(0~1)，无法判断时Lang为""
+type Detection struct {
+	Lang       string  `json:"lang"`
+	Confidence float64 `json:"confidence"`
+}
+
+// DetectLang 离线检测文本语言：先按文字系统判断，拉丁字母为主的文本再用三元组模型区分
+func DetectLang(text string) (string, float64) {
+	if lang, confidence := DetectScriptLang(text); lang != "" {
+		return lang, confidence
+	}
+
+	latin, letters := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.Is(unicode.Latin, r) {
+				latin++
+			}
+		}
+	}
+	if latin*2 < letters || latin == 0 {
+		return "", 0
+	}
+	lang, confidence := detectLatin(text)
+	return lang, confidence * float64(latin) / float64(letters)
+}
+
+// DetectLangs 逐条离线检测文本语言
+func DetectLangs(texts []string) []Detection {
+	result := make([]Detection, len(texts))
+	for i, text := range texts {
+		result[i].Lang, result[i].Confidence = DetectLang(text)
+	}
+	return result
+}
+
+// 常用字的简体和繁体写法，用于区分简体中文和繁体中文
+const (
+	simplifiedChars  = "这们个来时说国会对为学发过后还没经开关从见长现问书车东门马语话请让认识电点实体间动头条与业机网页应该设数据处务单号线"
+	traditionalChars = "這們個來時說國會對為學發過後還沒經開關從見長現問書車東門馬語話請讓認識電點實體間動頭條與業機網頁應該設數據處務單號線"
+)
+
+// ChineseVariant 按常用字判断中文文本是简体(Hans)还是繁体(Hant)，无法判断时返回""
+func ChineseVariant(text string) string {
+	hans, hant := 0, 0
+	for _, r := range text {
+		inHans, inHant := strings.ContainsRune(simplifiedChars, r), strings.ContainsRune(traditionalChars, r)
+		if inHans && !inHant {
+			hans++
+		} else if inHant && !inHans {
+			hant++
+		}
+	}
+	switch {
+	case hans > hant:
+		return "Hans"
+	case hant > hans:
+		return "Hant"
+	}
+	return ""
+}
+
+// MatchLang 判断语言代码lang是否属于langs之一，比如zh-CN属于zh
+func MatchLang(lang string, langs []string) bool {
+	if lang == "" {
+		return false
+	}
+	lang = strings.ReplaceAll(lang, "_", "-")
+	base, _, _ := strings.Cut(lang, "-")
+	for _, l := range langs {
+		if strings.EqualFold(lang, l) || strings.EqualFold(base, l) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 拉丁字母语言的样本文本，启动时统计成三元组模型
+var latinSamples = map[string]string{
+	"en": `All human beings are born free and equal in dignity and rights. They are endowed with reason and conscience and should act towards one another in a spirit of brotherhood.
+Everyone is entitled to all the rights and freedoms set forth in this declaration, without distinction of any kind. The weather was nice this morning, so we decided to walk to the office and have a coffee on the way.
+Please check the settings before you continue. This file could not be opened because it is being used by another program. What would you like to do next? I think that they have already left the house.`,
+	"fr": `Tous les êtres humains naissent libres et égaux en dignité et en droits. Ils sont doués de raison et de conscience et doivent agir les uns envers les autres dans un esprit de fraternité.
+Chacun peut se prévaloir de tous les droits et de toutes les libertés proclamés dans la présente déclaration, sans distinction aucune. Il faisait beau ce matin, alors nous avons décidé de marcher jusqu'au bureau et de prendre un café en chemin.
+Veuillez vérifier les paramètres avant de continuer. Ce fichier n'a pas pu être ouvert parce qu'il est utilisé par un autre programme. Que voulez-vous faire ensuite ? Je pense qu'ils sont déjà partis de la maison.`,
+	"de": `Alle Menschen sind frei und gleich an Würde und Rechten geboren. Sie sind mit Vernunft und Gewissen begabt und sollen einander im Geist der Brüderlichkeit begegnen.
+Jeder hat Anspruch auf alle in dieser Erklärung verkündeten Rechte und Freiheiten ohne irgendeinen Unterschied. Das Wetter war heute Morgen schön, deshalb haben wir beschlossen, zu Fuß ins Büro zu gehen und unterwegs einen Kaffee zu trinken.
+Bitte überprüfen Sie die Einstellungen, bevor Sie fortfahren. Diese Datei konnte nicht geöffnet werden, weil sie von einem anderen Programm verwendet wird. Was möchten Sie als Nächstes tun? Ich glaube, dass sie das Haus schon verlassen haben.`,
+	"es": `Todos los seres humanos nacen libres e iguales en dignidad y derechos y, dotados como están de razón y conciencia, deben comportarse fraternalmente los unos con los otros.
+Toda persona tiene todos los derechos y libertades proclamados en esta declaración, sin distinción alguna. Hacía buen tiempo esta mañana, así que decidimos caminar hasta la oficina y tomar un café por el camino.
+Por favor, compruebe la configuración antes de continuar. No se pudo abrir este archivo porque lo está usando otro programa. ¿Qué quiere hacer a continuación? Creo que ellos ya han salido de la casa.`,
+	"it": `Tutti gli esseri umani nascono liberi ed eguali in dignità e diritti. Essi sono dotati di ragione e di coscienza e devono agire gli uni verso gli altri in spirito di fratellanza.
+Ad ogni individuo spettano tutti i diritti e tutte le libertà enunciate nella presente dichiarazione, senza distinzione alcuna. Stamattina faceva bel tempo, quindi abbiamo deciso di andare a piedi in ufficio e di prendere un caffè lungo la strada.
+Si prega di controllare le impostazioni prima di continuare. Questo file non può essere aperto perché è utilizzato da un altro programma. Che cosa vuoi fare adesso? Penso che loro siano già usciti di casa.`,
+	"pt": `Todos os seres humanos nascem livres e iguais em dignidade e em direitos. Dotados de razão e de consciência, devem agir uns para com os outros em espírito de fraternidade.
+Todos os seres humanos podem invocar os direitos e as liberdades proclamados na presente declaração, sem distinção alguma. O tempo estava bom esta manhã, então decidimos ir a pé até o escritório e tomar um café no caminho.
+Por favor, verifique as configurações antes de continuar. Não foi possível abrir este arquivo porque ele está sendo usado por outro programa. O que você quer fazer agora? Acho que eles já saíram de casa.`,
+	"nl": `Alle mensen worden vrij en gelijk in waardigheid en rechten geboren. Zij zijn begiftigd met verstand en geweten, en behoren zich jegens elkander in een geest van broederschap te gedragen.
+Een ieder heeft aanspraak op alle rechten en vrijheden, uiteengezet in deze verklaring, zonder enig onderscheid. Het was mooi weer vanochtend, dus we besloten naar het kantoor te lopen en onderweg een kopje koffie te drinken.
+Controleer de instellingen voordat u verdergaat. Dit bestand kan niet worden geopend omdat het door een ander programma wordt gebruikt. Wat wilt u hierna doen? Ik denk dat ze het huis al hebben verlaten.`,
+	"sv": `Alla människor är födda fria och lika i värde och rättigheter. De har utrustats med förnuft och samvete och bör handla gentemot varandra i en anda av broderskap.
+Var och en är berättigad till alla de rättigheter och friheter som uttalas i denna förklaring utan åtskillnad av något slag. Vädret var fint i morse, så vi bestämde oss för att gå till kontoret och dricka en kopp kaffe på vägen.
+Kontrollera inställningarna innan du fortsätter. Filen kunde inte öppnas eftersom den används av ett annat program. Vad vill du göra härnäst? Jag tror att de redan har lämnat huset.`,
+	"pl": `Wszyscy ludzie rodzą się wolni i równi pod względem swej godności i swych praw. Są oni obdarzeni rozumem i sumieniem i powinni postępować wobec innych w duchu braterstwa.
+Każdy człowiek posiada wszystkie prawa i wolności zawarte w niniejszej deklaracji bez względu na jakiekolwiek różnice. Dziś rano była ładna pogoda, więc postanowiliśmy pójść do biura pieszo i po drodze napić się kawy.
+Sprawdź ustawienia przed kontynuowaniem. Nie można otworzyć tego pliku, ponieważ jest używany przez inny program. Co chcesz zrobić dalej? Myślę, że oni już wyszli z domu.`,
+	"cs": `Všichni lidé rodí se svobodní a sobě rovní co do důstojnosti a práv. Jsou nadáni rozumem a svědomím a mají spolu jednat v duchu bratrství.
+Každý má všechna práva a všechny svobody stanovené touto deklarací bez jakéhokoli rozlišování. Dnes ráno bylo hezké počasí, a tak jsme se rozhodli jít do kanceláře pěšky a cestou si dát kávu.
+Před pokračováním prosím zkontrolujte nastavení. Tento soubor nelze otevřít, protože jej používá jiný program. Co chcete dělat dál? Myslím, že už odešli z domu.`,
+	"tr": `Bütün insanlar hür, haysiyet ve haklar bakımından eşit doğarlar. Akıl ve vicdana sahiptirler ve birbirlerine karşı kardeşlik zihniyeti ile hareket etmelidirler.
+Herkes, bu beyannamede ilan olunan tüm haklardan ve bütün özgürlüklerden hiçbir ayrım gözetilmeksizin yararlanabilir. Bu sabah hava güzeldi, bu yüzden ofise yürümeye ve yolda bir kahve içmeye karar verdik.
+Lütfen devam etmeden önce ayarları kontrol edin. Bu dosya başka bir program tarafından kullanıldığı için açılamadı. Bundan sonra ne yapmak istersiniz? Sanırım onlar evden çoktan çıktılar.`,
+	"vi": `Tất cả mọi người sinh ra đều được tự do và bình đẳng về nhân phẩm và quyền lợi. Mọi con người đều được tạo hóa ban cho lý trí và lương tâm và cần phải đối xử với nhau trong tình anh em.
+Mọi người đều được hưởng tất cả các quyền và tự do nêu trong bản tuyên ngôn này, không có bất kỳ sự phân biệt nào. Sáng nay trời đẹp nên chúng tôi quyết định đi bộ đến văn phòng và uống một tách cà phê trên đường.
+Vui lòng kiểm tra cài đặt trước khi tiếp tục. Không thể mở tệp này vì nó đang được một chương trình khác sử dụng. Bạn muốn làm gì tiếp theo? Tôi nghĩ rằng họ đã rời khỏi nhà rồi.`,
+	"id": `Semua orang dilahirkan merdeka dan mempunyai martabat dan hak-hak yang sama. Mereka dikaruniai akal dan hati nurani dan hendaknya bergaul satu sama lain dalam semangat persaudaraan.
+Setiap orang berhak atas semua hak dan kebebasan yang tercantum di dalam pernyataan ini dengan tidak ada kekecualian apa pun. Cuaca pagi ini cerah, jadi kami memutuskan untuk berjalan kaki ke kantor dan minum kopi di jalan.
+Silakan periksa pengaturan sebelum melanjutkan. Berkas ini tidak dapat dibuka karena sedang digunakan oleh program lain. Apa yang ingin Anda lakukan selanjutnya? Saya pikir mereka sudah meninggalkan rumah.`,
+	"fi": `Kaikki ihmiset syntyvät vapaina ja tasavertaisina arvoltaan ja oikeuksiltaan. Heille on annettu järki ja omatunto, ja heidän on toimittava toisiaan kohtaan veljeyden hengessä.
+Jokainen on oikeutettu kaikkiin tässä julistuksessa esitettyihin oikeuksiin ja vapauksiin ilman minkäänlaista erotusta. Sää oli aamulla kaunis, joten päätimme kävellä toimistolle ja juoda kupin kahvia matkalla.
+Tarkista asetukset ennen kuin jatkat. Tiedostoa ei voitu avata, koska toinen ohjelma käyttää sitä. Mitä haluat tehdä seuraavaksi? Luulen, että he ovat jo lähteneet kotoa.`,
+	"hu": `Minden emberi lény szabadon születik és egyenlő méltósága és joga van. Az emberek, ésszel és lelkiismerettel bírván, egymással szemben testvéri szellemben kell hogy viseltessenek.
+Mindenki, bármely megkülönböztetésre való tekintet nélkül hivatkozhat a jelen nyilatkozatban kinyilvánított összes jogokra és szabadságokra. Ma reggel szép idő volt, ezért úgy döntöttünk, hogy gyalog megyünk az irodába, és útközben megiszunk egy kávét.
+Kérjük, a folytatás előtt ellenőrizze a beállításokat. Ezt a fájlt nem lehet megnyitni, mert egy másik program használja. Mit szeretne most csinálni? Azt hiszem, már elmentek otthonról.`,
+	"ro": `Toate ființele umane se nasc libere și egale în demnitate și în drepturi. Ele sunt înzestrate cu rațiune și conștiință și trebuie să se comporte unele față de altele în spiritul fraternității.
+Fiecare om se poate prevala de toate drepturile și libertățile proclamate în prezenta declarație fără niciun fel de deosebire. Vremea a fost frumoasă azi-dimineață, așa că am hotărât să mergem pe jos la birou și să bem o cafea pe drum.
+Vă rugăm să verificați setările înainte de a continua. Acest fișier nu a putut fi deschis deoarece este folosit de un alt program. Ce doriți să faceți în continuare? Cred că ei au plecat deja de acasă.`,
+}
+
+// ngramModel 一种语言的三元组对数概率，未出现的三元组用unseen
+type ngramModel struct {
+	lang   string
+	logP   map[string]float64
+	unseen float64
+}
+
+var (
+	latinModels     []ngramModel
+	latinModelsOnce sync.Once
+)
+
+// trigrams 将文本按单词切分，小写后首尾补空格，统计其中的三元组
+func trigrams(text string) map[string]int {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, w := range words {
+		rs := []rune(" " + w + " ")
+		for i := 0; i+3 <= len(rs); i++ {
+			counts[string(rs[i:i+3])]++
+		}
+	}
+	return counts
+}
+
+func loadLatinModels() []ngramModel {
+	latinModelsOnce.Do(func() {
+		for lang, sample := range latinSamples {
+			counts := trigrams(sample)
+			total := 0
+			for _, c := range counts {
+				total += c
+			}
+			// 加一平滑
+			denom := float64(total + len(counts) + 1)
+			m := ngramModel{lang: lang, logP: make(map[string]float64, len(counts)), unseen: math.Log(1 / denom)}
+			for g, c := range counts {
+				m.logP[g] = math.Log(float64(c+1) / denom)
+			}
+			latinModels = append(latinModels, m)
+		}
+		slices.SortFunc(latinModels, func(a, b ngramModel) int { return strings.Compare(a.lang, b.lang) })
+	})
+	return latinModels
+}
+
+// detectLatin 用三元组模型区分拉丁字母语言，返回语言代码和后验概率
+func detectLatin(text string) (string, float64) {
+	counts := trigrams(text)
+	if len(counts) == 0 {
+		return "", 0
+	}
+
+	models := loadLatinModels()
+	scores := make([]float64, len(models))
+	best := 0
+	for i, m := range models {
+		for g, c := range counts {
+			p, ok := m.logP[g]
+			if !ok {
+				p = m.unseen
+			}
+			scores[i] += float64(c) * p
+		}
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+
+	// 各语言等先验时的后验概率
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - scores[best])
+	}
+	return models[best].lang, 1 / sum
+}
@@ -0,0 +1,78 @@
+package util
+
+import "testing"
+
+func TestDetectScriptLang(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"你好，世界", "zh"},
+		{"こんにちは世界", "ja"},
+		{"안녕하세요", "ko"},
+		{"Привет, мир", "ru"},
+		{"Γειά σου κόσμε", "el"},
+		{"Hello, world", ""},
+		{"12345", ""},
+		{"Hello 世界 and more words", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := DetectScriptLang(tt.text); got != tt.want {
+			t.Errorf("DetectScriptLang(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLang(t *testing.T) {
+	tests := []struct {
+		lang  string
+		langs []string
+		want  bool
+	}{
+		{"ja", []string{"ja", "ko"}, true},
+		{"zh-CN", []string{"zh"}, true},
+		{"zh_TW", []string{"zh-TW"}, true},
+		{"zh-TW", []string{"zh-tw"}, true},
+		{"en", []string{"de", "fr"}, false},
+		{"", []string{"en"}, false},
+	}
+	for _, tt := range tests {
+		if got := MatchLang(tt.lang, tt.langs); got != tt.want {
+			t.Errorf("MatchLang(%q, %v) = %v, want %v", tt.lang, tt.langs, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLang(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The quick brown fox jumps over the lazy dog", "en"},
+		{"Where is the nearest train station?", "en"},
+		{"Je voudrais réserver une table pour deux personnes ce soir", "fr"},
+		{"Ich habe keine Zeit, weil ich heute arbeiten muss", "de"},
+		{"¿Dónde está la estación de tren más cercana?", "es"},
+		{"Vorrei prenotare un tavolo per due persone stasera", "it"},
+		{"Eu gostaria de reservar uma mesa para duas pessoas", "pt"},
+		{"Ik wil graag een tafel reserveren voor twee personen", "nl"},
+		{"Jag skulle vilja boka ett bord för två personer ikväll", "sv"},
+		{"Chciałbym zarezerwować stolik dla dwóch osób na dzisiaj", "pl"},
+		{"Bu akşam iki kişilik bir masa ayırtmak istiyorum", "tr"},
+		{"Tôi muốn đặt một bàn cho hai người tối nay", "vi"},
+		{"Saya ingin memesan meja untuk dua orang malam ini", "id"},
+		{"你好，世界", "zh"},
+		{"こんにちは世界", "ja"},
+		{"12345", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, confidence := DetectLang(tt.text)
+		if got != tt.want {
+			t.Errorf("DetectLang(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if got != "" && (confidence <= 0 || confidence > 1) {
+			t.Errorf("DetectLang(%q) confidence = %v, want in (0, 1]", tt.text, confidence)
+		}
+	}
+}